@@ -0,0 +1,105 @@
+package gosert
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Assert asserts that actual (a `string` or `[]byte`) matches golden.  Every mismatch is reported with t.Errorf.
+// Returns true if actual matches.
+func Assert(t testing.TB, golden []byte, actual interface{}, opts ...Option) bool {
+	t.Helper()
+	m, err := New(golden, opts...)
+	if err != nil {
+		t.Errorf("gosert: invalid golden value: %s", err.Error())
+		return false
+	}
+	return assertMatcher(t, m, actual)
+}
+
+// AssertFile asserts that actual (a `string` or `[]byte`) matches the golden file at path.  Every mismatch is reported
+// with t.Errorf.  Returns true if actual matches.
+//
+// If updates are turned on (see WithUpdate), mismatches are not reported.  Instead the golden file is overwritten with
+// actual when the test finishes, and the rewritten values are logged.  Golden files with variables, functions or markers
+// are never overwritten, because they would be lost.  Their mismatches are reported as usual.
+func AssertFile(t testing.TB, path string, actual interface{}, opts ...Option) bool {
+	t.Helper()
+	o := newOptions(opts)
+	m, err := NewFromFile(path, opts...)
+	if err != nil && !(o.update && os.IsNotExist(err)) {
+		t.Errorf("gosert: invalid golden file %s: %s", path, err.Error())
+		return false
+	}
+	if o.update {
+		var rewritten []string
+		if m != nil {
+			if matched, err := m.Match(actual); matched && err == nil {
+				return true
+			}
+			if bs, err := ioutil.ReadFile(path); err == nil && hasExpressions(bs) {
+				// Overwriting would erase the expressions
+				t.Errorf("gosert: cannot update golden file %s because it contains variables, functions or markers, update it by hand", path)
+				return assertMatcher(t, m, actual)
+			}
+			for _, f := range m.failures {
+				rewritten = append(rewritten, f.Message)
+			}
+		}
+		t.Cleanup(func() {
+			if err := ioutil.WriteFile(path, prettyBytes(actual), 0644); err != nil {
+				t.Errorf("gosert: cannot update golden file %s: %s", path, err.Error())
+				return
+			}
+			if m == nil {
+				t.Logf("gosert: created golden file %s", path)
+				return
+			}
+			t.Logf("gosert: updated golden file %s:\n%s", path, strings.Join(rewritten, "\n"))
+		})
+		return false
+	}
+	return assertMatcher(t, m, actual)
+}
+
+func assertMatcher(t testing.TB, m *Matcher, actual interface{}) bool {
+	t.Helper()
+	matched, err := m.Match(actual)
+	if matched && err == nil {
+		return true
+	}
+	for _, f := range m.failures {
+		t.Errorf("gosert: %s", f.FailureMessage(actual))
+	}
+	if len(m.failures) == 0 && err != nil {
+		t.Errorf("gosert: %s", err.Error())
+	}
+	return false
+}
+
+// hasExpressions returns true if the golden value golden has anything besides literal values, e.g. variables,
+// functions, markers or includes.
+func hasExpressions(golden []byte) bool {
+	return bytes.Contains(golden, []byte("{{")) || bytes.Contains(golden, []byte("_gst_")) || bytes.Contains(golden, []byte(`"$ref"`))
+}
+
+// prettyBytes returns actual indented, if it is JSON.
+func prettyBytes(actual interface{}) []byte {
+	var bs []byte
+	switch a := actual.(type) {
+	case []byte:
+		bs = a
+	case string:
+		bs = []byte(a)
+	}
+	var buf bytes.Buffer
+	if json.Indent(&buf, bs, "", "  ") != nil {
+		return bs
+	}
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,121 @@
+package gosert
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeT records errors instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func TestAssert(t *testing.T) {
+	golden := []byte(`{"foo": "${{FOO}}", "bar": "{{Not(BeEmpty())}}", "baz": 1}`)
+
+	ft := &fakeT{TB: t}
+	if !Assert(ft, golden, `{"foo": "foo", "bar": "bar", "baz": 1}`, WithVars(map[string]string{"FOO": "foo"})) {
+		t.Fatalf("Assert() should succeed but failed with %+v", ft.errors)
+	}
+
+	ft = &fakeT{TB: t}
+	if Assert(ft, golden, `{"foo": "qux", "bar": "", "baz": 1}`, WithVars(map[string]string{"FOO": "foo"})) {
+		t.Fatalf("Assert() should fail")
+	}
+	if len(ft.errors) != 2 || !strings.Contains(ft.errors[0], "path = .bar") || !strings.Contains(ft.errors[1], "path = .foo") {
+		t.Fatalf("Assert() should report all mismatches but reported %+v", ft.errors)
+	}
+}
+
+func TestAssertFile(t *testing.T) {
+	ft := &fakeT{TB: t}
+	act := `{
+			"field0": "value0",
+			"field1": {
+				"field1_0": "value1_0",
+				"field1_1": {
+					"field1_1_0": "2018-10-05T12:13:14.123Z"
+				}
+			}
+		}`
+	vars := WithVars(map[string]string{
+		"VAR":       "value1_0",
+		"TIMESTAMP": "2018-10-05T12:13:14.000Z",
+	})
+	if !AssertFile(ft, "test_asset/golden1.json", act, vars, WithUpdate(false)) {
+		t.Fatalf("AssertFile() should succeed but failed with %+v", ft.errors)
+	}
+}
+
+func TestAssertFile_Update(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosert")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "golden.json")
+
+	ft := &fakeT{TB: t}
+	if AssertFile(ft, path, `{"foo":"bar"}`, WithUpdate(true)) {
+		t.Fatalf("AssertFile() should not match a missing golden file")
+	}
+	if len(ft.errors) != 0 || len(ft.cleanups) != 1 {
+		t.Fatalf("AssertFile() should register an update, errors = %+v", ft.errors)
+	}
+	ft.cleanups[0]()
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if string(bs) != "{\n  \"foo\": \"bar\"\n}\n" {
+		t.Fatalf("golden file incorrect, %s", string(bs))
+	}
+
+	ft = &fakeT{TB: t}
+	if !AssertFile(ft, path, `{"foo": "bar"}`) {
+		t.Fatalf("AssertFile() should succeed but failed with %+v", ft.errors)
+	}
+
+	// Rewritten values are logged
+	ft = &fakeT{TB: t}
+	AssertFile(ft, path, `{"foo": "baz"}`, WithUpdate(true))
+	ft.cleanups[0]()
+	if len(ft.logs) != 1 || !strings.Contains(ft.logs[0], "path = .foo, expected = bar, actual = baz") {
+		t.Fatalf("AssertFile() should log the update, logs = %+v", ft.logs)
+	}
+
+	// Golden files with expressions are not overwritten
+	golden := []byte(`{"foo": "{{Not(BeEmpty())}}", "bar": "bar"}`)
+	if err := ioutil.WriteFile(path, golden, 0644); err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	ft = &fakeT{TB: t}
+	if AssertFile(ft, path, `{"foo": "baz", "bar": "qux"}`, WithUpdate(true)) {
+		t.Fatalf("AssertFile() should fail")
+	}
+	if len(ft.cleanups) != 0 || len(ft.errors) != 2 || !strings.Contains(ft.errors[0], "cannot update golden file") || !strings.Contains(ft.errors[1], "path = .bar") {
+		t.Fatalf("AssertFile() should report mismatches, errors = %+v", ft.errors)
+	}
+}
@@ -0,0 +1,77 @@
+package gosert
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Case is a table-driven test case in a multipart file: an input section and an expected section.
+type Case struct {
+	// Name is the key of the sections without the role, e.g. "case1"
+	Name string
+	// Input is the data of the input section
+	Input []byte
+	// Matcher matches the expected section
+	Matcher *Matcher
+}
+
+// Cases returns the cases in the file, in the order of their input sections.  A case is a pair of sections with keys
+// <name>_input and <name>_expected, which can also be written as "### key=<name>, role=input" and
+// "### key=<name>, role=expected".  opts configure the matchers, the parser of the expected section is always used.
+//
+// Returns an error if a case has only one of the sections.
+func (r *MultipartReader) Cases(opts ...Option) ([]Case, error) {
+	var cases []Case
+	for _, key := range r.Keys() {
+		if name := strings.TrimSuffix(key, "_"+RoleExpected); name != key {
+			if _, ok := r.sources[name+"_"+RoleInput]; !ok {
+				return nil, fmt.Errorf("case '%s' has no input section '%s_%s'.  See Gosert doc.", name, name, RoleInput)
+			}
+			continue
+		}
+		name := strings.TrimSuffix(key, "_"+RoleInput)
+		if name == key {
+			continue
+		}
+		expected := name + "_" + RoleExpected
+		source, ok := r.sources[expected]
+		if !ok {
+			return nil, fmt.Errorf("case '%s' has no expected section '%s'.  See Gosert doc.", name, expected)
+		}
+		cases = append(cases, Case{
+			Name:    name,
+			Input:   r.parts[key],
+			Matcher: newMatcher(source, newOptions(append(opts[:len(opts):len(opts)], WithParser(r.parsers[expected])))),
+		})
+	}
+	return cases, nil
+}
+
+// RunCases runs a subtest for each case in the multipart file at path (see MultipartReader.Cases).  fn returns the
+// actual value (a `string` or `[]byte`) for the input of a case, which is asserted to match the expected value.
+//
+//	gosert.RunCases(t, "testdata/orders.txt", func(t *testing.T, input []byte) interface{} {
+//		return handle(input)
+//	}, gosert.WithVars(vars))
+//
+// opts configure the reader (WithVars and WithParser) and the matchers.
+func RunCases(t *testing.T, path string, fn func(t *testing.T, input []byte) interface{}, opts ...Option) {
+	t.Helper()
+	o := newOptions(opts)
+	r, err := NewMultipartReaderFromFile(path, o.vars, o.parser)
+	if err != nil {
+		t.Fatalf("gosert: invalid golden file %s: %s", path, err.Error())
+	}
+	cases, err := r.Cases(opts...)
+	if err != nil {
+		t.Fatalf("gosert: invalid golden file %s: %s", path, err.Error())
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			t.Helper()
+			assertMatcher(t, c.Matcher, fn(t, c.Input))
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package gosert
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+// echo adds an ID to the JSON object input.
+func echo(input []byte) []byte {
+	m := map[string]interface{}{}
+	json.Unmarshal(input, &m)
+	m["id"] = "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	bs, _ := json.Marshal(m)
+	return bs
+}
+
+func TestMultipartReader_Cases(t *testing.T) {
+	r := MustReader(NewMultipartReaderFromFile("test_asset/cases1.txt", map[string]string{"CONNECTION": "0002"}, matcher.JSONParserInstance))
+	cases, err := r.Cases(WithStrictObjects(true))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if len(cases) != 2 || cases[0].Name != "case1" || cases[1].Name != "case2" {
+		t.Fatalf("cases incorrect: %+v", cases)
+	}
+	matched, _ := cases[0].Matcher.Match(echo(cases[0].Input))
+	if !matched {
+		t.Fatalf("matched should be true but failed with %s", cases[0].Matcher.FailureMessage(nil))
+	}
+	// Matchers are configured with opts
+	matched, _ = cases[0].Matcher.Match(`{"id": "f47ac10b-58cc-4372-a567-0e02b2c3d479", "name": "Ethan Hunt", "age": 36}`)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	matched, _ = cases[1].Matcher.Match(echo(cases[1].Input))
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if r.GetData("case2_input") == nil {
+		t.Fatalf("sections with role should have the role in the key")
+	}
+}
+
+func TestMultipartReader_CasesErrors(t *testing.T) {
+	cases := []struct {
+		data     string
+		expected string
+	}{
+		{"### key=a_input\n{}", "case 'a' has no expected section 'a_expected'.  See Gosert doc."},
+		{"### key=a, role=expected\n{}", "case 'a' has no input section 'a_input'.  See Gosert doc."},
+	}
+	for _, c := range cases {
+		r := MustReader(NewMultipartReader([]byte(c.data), nil, matcher.JSONParserInstance))
+		if _, err := r.Cases(); err == nil || err.Error() != c.expected {
+			t.Fatalf("err of %s incorrect: %+v", c.data, err)
+		}
+	}
+
+	_, err := NewMultipartReader([]byte("### key=a, role=output\n{}"), nil, matcher.JSONParserInstance)
+	if err == nil || err.Error() != "section 'a' has invalid role=output, must be input or expected" {
+		t.Fatalf("err incorrect: %+v", err)
+	}
+}
+
+func TestRunCases(t *testing.T) {
+	var names []string
+	RunCases(t, "test_asset/cases1.txt", func(t *testing.T, input []byte) interface{} {
+		names = append(names, t.Name())
+		return echo(input)
+	}, WithVars(map[string]string{"CONNECTION": "0001"}))
+	if len(names) != 2 || names[0] != "TestRunCases/case1" || names[1] != "TestRunCases/case2" {
+		t.Fatalf("subtests incorrect: %v", names)
+	}
+}
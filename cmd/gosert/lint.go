@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/mina-akimi/gosert/v2"
+)
+
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "json", "format of the golden files: json or yaml")
+	vars := varsFlag{}
+	fs.Var(vars, "vars", "variable `key=value` to replace in the golden files, can be repeated.  If given, undefined variables are reported")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gosert lint [flags] file...")
+		fmt.Fprintln(stderr, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitMatch
+		}
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+	parser, ok := parsers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "gosert: unknown format %s, must be json or yaml\n", *format)
+		return exitError
+	}
+	var opts []gosert.Option
+	if len(vars) > 0 {
+		opts = append(opts, gosert.WithVars(vars))
+	}
+
+	code := exitMatch
+	for _, path := range fs.Args() {
+		diags, err := gosert.ValidateFile(path, parser, opts...)
+		if err != nil {
+			fmt.Fprintf(stderr, "gosert: %s\n", err.Error())
+			return exitError
+		}
+		for _, d := range diags {
+			fmt.Fprintln(stdout, d.String())
+		}
+		if len(diags) > 0 {
+			code = exitMismatch
+		}
+	}
+	return code
+}
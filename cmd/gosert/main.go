@@ -0,0 +1,55 @@
+// Command gosert matches payloads against golden files outside of go test.
+//
+// Usage:
+//
+//	gosert match --golden file.json [--section key] [--vars k=v]... [--format json|yaml] [--report text|json|junit] [actual.json]
+//	gosert lint [--vars k=v]... [--format json|yaml] file...
+//
+// For match, the actual payload is read from stdin if no file is given.  The exit code is 0 if the payload matches, 1
+// if it doesn't and 2 on errors.  For lint, the exit code is 0 if the golden files have no problems, 1 if they do and 2
+// on errors.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	exitMatch    = 0
+	exitMismatch = 1
+	exitError    = 2
+)
+
+const usage = `Usage: gosert <command> [flags]
+
+Commands:
+  match    match a payload against a golden file
+  lint     check golden files for problems
+
+Run 'gosert <command> -h' for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command in args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	switch args[0] {
+	case "match":
+		return runMatch(args[1:], stdin, stdout, stderr)
+	case "lint":
+		return runLint(args[1:], stdout, stderr)
+	case "-h", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return exitMatch
+	}
+	fmt.Fprintf(stderr, "gosert: unknown command %s\n\n%s", args[0], usage)
+	return exitError
+}
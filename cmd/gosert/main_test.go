@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const actual1 = `{
+	"field0": "value0",
+	"field1": {
+		"field1_0": "value1_0",
+		"field1_1": {
+			"field1_1_0": "2018-10-05T12:13:14.123Z"
+		}
+	}
+}`
+
+func runCommand(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Match(t *testing.T) {
+	code, stdout, stderr := runCommand(actual1, "match", "--golden", "../../test_asset/golden1.json", "--vars", "VAR=value1_0", "--vars", "TIMESTAMP=2018-10-05T12:13:14.000Z")
+	if code != exitMatch || stdout != "OK\n" {
+		t.Fatalf("code = %d, stdout = %s, stderr = %s", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCommand(actual1, "match", "--golden", "../../test_asset/golden1.json", "--vars", "VAR=other", "--vars", "TIMESTAMP=2018-10-05T12:13:14.000Z")
+	if code != exitMismatch || !strings.Contains(stdout, "path = .field1.field1_0, expected = other, actual = value1_0, at ../../test_asset/golden1.json:4:17") {
+		t.Fatalf("code = %d, stdout = %s", code, stdout)
+	}
+
+	code, stdout, _ = runCommand(actual1, "match", "--golden", "../../test_asset/golden1.json", "--vars", "VAR=other", "--vars", "TIMESTAMP=2018-10-05T12:13:14.000Z", "--report", "json")
+	if code != exitMismatch || !strings.Contains(stdout, `"path": ".field1.field1_0"`) {
+		t.Fatalf("code = %d, stdout = %s", code, stdout)
+	}
+}
+
+func TestRun_Match_File(t *testing.T) {
+	code, stdout, stderr := runCommand("", "match", "--golden", "../../test_asset/golden1.json", "--vars", "VAR=value1_0", "--vars", "TIMESTAMP=2018-10-05T12:13:14.000Z", "--report", "junit", "../../test_asset/golden1.json")
+	if code != exitMismatch || !strings.Contains(stdout, "<testsuite name=\"gosert match\" tests=\"1\" failures=\"1\">") {
+		t.Fatalf("code = %d, stdout = %s, stderr = %s", code, stdout, stderr)
+	}
+}
+
+func TestRun_Match_Section(t *testing.T) {
+	code, stdout, stderr := runCommand(`{"id": "0001", "connections": [{"id": "0002", "name": "Julia Meade"}, {"id": "0003", "name": "Ilsa Faust"}]}`,
+		"match", "--golden", "../../test_asset/multipart1.txt", "--section", "my_matcher", "--vars", "ID=0001")
+	if code != exitMismatch || !strings.Contains(stdout, "path = .connections.id=0003.name, expected = Benji Dunn, actual = Ilsa Faust, at ../../test_asset/multipart1.txt:23:15") {
+		t.Fatalf("code = %d, stdout = %s, stderr = %s", code, stdout, stderr)
+	}
+}
+
+func TestRun_Match_YAML(t *testing.T) {
+	actual := `
+field0: value0
+field1:
+  field1_0: value1_0
+  field1_1:
+    field1_1_0: 2018-10-05T12:13:14.123Z
+`
+	code, stdout, stderr := runCommand(actual, "match", "--golden", "../../test_asset/golden1.yaml", "--format", "yaml", "--vars", "VAR=value1_0", "--vars", "TIMESTAMP=2018-10-05T12:13:14.000Z")
+	if code != exitMatch {
+		t.Fatalf("code = %d, stdout = %s, stderr = %s", code, stdout, stderr)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	cases := []struct {
+		args   []string
+		stderr string
+	}{
+		{nil, "Usage: gosert <command>"},
+		{[]string{"unknown"}, "unknown command unknown"},
+		{[]string{"match"}, "Usage: gosert match"},
+		{[]string{"match", "--golden", "../../test_asset/golden1.json", "--format", "xml"}, "unknown format xml"},
+		{[]string{"match", "--golden", "../../test_asset/golden1.json", "--vars", "VAR"}, "must have format key=value"},
+		{[]string{"match", "--golden", "../../test_asset/golden1.json"}, "variable 'VAR' undefined"},
+		{[]string{"match", "--golden", "../../test_asset/multipart1.txt", "--section", "nope", "--vars", "ID=1"}, "no such key 'nope'"},
+		{[]string{"match", "--golden", "missing.json"}, "no such file or directory"},
+	}
+	for _, c := range cases {
+		code, _, stderr := runCommand("", c.args...)
+		if code != exitError || !strings.Contains(stderr, c.stderr) {
+			t.Fatalf("%v: code = %d, stderr = %s", c.args, code, stderr)
+		}
+	}
+}
+
+func TestRun_Lint(t *testing.T) {
+	code, stdout, stderr := runCommand("", "lint", "../../test_asset/multipart1.txt", "../../test_asset/golden1.json")
+	if code != exitMatch || stdout != "" {
+		t.Fatalf("code = %d, stdout = %s, stderr = %s", code, stdout, stderr)
+	}
+
+	code, stdout, _ = runCommand("", "lint", "--vars", "ID=0001", "../../test_asset/multipart1.txt", "../../test_asset/lint1.txt")
+	if code != exitMismatch || !strings.HasPrefix(stdout, "../../test_asset/lint1.txt:8:1: section header has no key=\n") || strings.Contains(stdout, "multipart1.txt") {
+		t.Fatalf("code = %d, stdout = %s", code, stdout)
+	}
+
+	code, _, stderr = runCommand("", "lint", "no_such_file.json")
+	if code != exitError || !strings.Contains(stderr, "no_such_file.json") {
+		t.Fatalf("code = %d, stderr = %s", code, stderr)
+	}
+}
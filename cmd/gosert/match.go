@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/mina-akimi/gosert/v2"
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+// varsFlag collects repeated k=v flags.
+type varsFlag map[string]string
+
+// String implements flag.Value.
+func (v varsFlag) String() string {
+	var pairs []string
+	for k, val := range v {
+		pairs = append(pairs, k+"="+val)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value.
+func (v varsFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return fmt.Errorf("must have format key=value, was %s", s)
+	}
+	v[s[:i]] = s[i+1:]
+	return nil
+}
+
+var parsers = map[string]matcher.Parser{
+	"json": matcher.JSONParserInstance,
+	"yaml": matcher.YAMLParserInstance,
+}
+
+func runMatch(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("match", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	golden := fs.String("golden", "", "golden file (required)")
+	section := fs.String("section", "", "key of the section in a multipart golden file")
+	format := fs.String("format", "json", "format of the golden file and the payload: json or yaml")
+	report := fs.String("report", "text", "format of the failure report: text, json or junit")
+	vars := varsFlag{}
+	fs.Var(vars, "vars", "variable `key=value` to replace in the golden file, can be repeated")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: gosert match --golden file.json [flags] [actual.json]")
+		fmt.Fprintln(stderr, "\nThe payload is read from stdin if no file is given.\n\nFlags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitMatch
+		}
+		return exitError
+	}
+	if *golden == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return exitError
+	}
+	parser, ok := parsers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "gosert: unknown format %s, must be json or yaml\n", *format)
+		return exitError
+	}
+	if *report != "text" && *report != "json" && *report != "junit" {
+		fmt.Fprintf(stderr, "gosert: unknown report %s, must be text, json or junit\n", *report)
+		return exitError
+	}
+
+	m, err := newMatcher(*golden, *section, vars, parser)
+	if err != nil {
+		fmt.Fprintf(stderr, "gosert: %s\n", err.Error())
+		return exitError
+	}
+	var actual []byte
+	if fs.NArg() == 0 || fs.Arg(0) == "-" {
+		actual, err = ioutil.ReadAll(stdin)
+	} else {
+		actual, err = ioutil.ReadFile(fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gosert: %s\n", err.Error())
+		return exitError
+	}
+
+	matched, matchErr := m.Match(actual)
+	if err := printReport(stdout, *report, m, actual, matched); err != nil {
+		fmt.Fprintf(stderr, "gosert: %s\n", err.Error())
+		return exitError
+	}
+	if matchErr != nil && *report == "text" {
+		fmt.Fprintf(stdout, "error: %s\n", matchErr.Error())
+	}
+	if !matched {
+		return exitMismatch
+	}
+	return exitMatch
+}
+
+func newMatcher(golden, section string, vars map[string]string, parser matcher.Parser) (*gosert.Matcher, error) {
+	if section == "" {
+		return gosert.NewMatcherFromFile(golden, vars, parser)
+	}
+	r, err := gosert.NewMultipartReaderFromFile(golden, vars, parser)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetMatcher(section)
+}
+
+func printReport(w io.Writer, format string, m *gosert.Matcher, actual []byte, matched bool) error {
+	switch format {
+	case "json":
+		bs, err := m.Report().JSON()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(bs))
+		return err
+	case "junit":
+		bs, err := m.Report().JUnit("gosert match")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(bs))
+		return err
+	}
+	if matched {
+		_, err := fmt.Fprintln(w, "OK")
+		return err
+	}
+	_, err := fmt.Fprintln(w, m.FailureMessage(actual))
+	return err
+}
@@ -1,10 +1,11 @@
 module github.com/mina-akimi/gosert/v2
 
-go 1.12
+go 1.14
 
 require (
 	github.com/buger/jsonparser v0.0.0-20180910192245-6acdf747ae99
 	github.com/onsi/gomega v1.4.2
+	gopkg.in/yaml.v2 v2.2.1
 )
 
 replace github.com/mina-akimi/gosert/matcher => ./matcher
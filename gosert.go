@@ -3,6 +3,7 @@ package gosert
 import (
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/mina-akimi/gosert/v2/matcher"
 	"github.com/onsi/gomega/types"
@@ -10,36 +11,41 @@ import (
 
 // Matcher implements types.GomegaMatcher
 type Matcher struct {
-	parser   matcher.Parser
+	opts     *options
 	expected matcher.Node
+	// source of expected, used to locate failures in the golden file
+	source *matcher.Source
 	// a current matcher that we delegate failure message to
 	curMatcher types.GomegaMatcher
+	// failures found by the last match
+	failures []*matcher.FailureMatcher
 }
 
-// NewMatcher returns a new matcher.  vars is used to replace variables in data.
-func NewMatcher(data []byte, vars map[string]string, parser matcher.Parser) (*Matcher, error) {
-	data, err := matcher.Replace(data, vars)
+// New returns a new matcher configured with opts.
+//
+//	m, err := New(data, WithVars(vars), WithStrictObjects(true))
+func New(data []byte, opts ...Option) (*Matcher, error) {
+	return newMatcherFromData("", data, newOptions(opts))
+}
+
+// NewFromFile returns a new matcher configured with opts.  The expected value is read from path.
+func NewFromFile(path string, opts ...Option) (*Matcher, error) {
+	bs, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Matcher{
-		expected: matcher.Node{
-			Type:  matcher.Object,
-			Value: data,
-		},
-		parser: parser,
-	}, err
+	return newMatcherFromData(path, bs, newOptions(opts))
+}
+
+// NewMatcher returns a new matcher.  vars is used to replace variables in data.
+func NewMatcher(data []byte, vars map[string]string, parser matcher.Parser) (*Matcher, error) {
+	return New(data, WithVars(vars), WithParser(parser))
 }
 
 // NewMatcher returns a new matcher.  The expected value is read from path.  vars is used to replace variables in data.
 func NewMatcherFromFile(path string, vars map[string]string, parser matcher.Parser) (*Matcher, error) {
-	bs, err := ioutil.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-
-	return NewMatcher(bs, vars, parser)
+	return NewFromFile(path, WithVars(vars), WithParser(parser))
 }
 
 // NewJSONMatcher returns a new matcher.
@@ -52,6 +58,27 @@ func NewJSONMatcherFromFile(path string, vars map[string]string) (*Matcher, erro
 	return NewMatcherFromFile(path, vars, matcher.JSONParserInstance)
 }
 
+func newMatcherFromData(name string, data []byte, opts *options) (*Matcher, error) {
+	now := opts.clock()
+	gen := matcher.NewGenerator(now.UnixNano(), now)
+	source, err := newSource(name, sourceID(name, ""), data, nil, opts.vars, gen, newIncluder(opts.vars, gen))
+	if err != nil {
+		return nil, err
+	}
+	return newMatcher(source, opts), nil
+}
+
+func newMatcher(source *matcher.Source, opts *options) *Matcher {
+	return &Matcher{
+		expected: matcher.Node{
+			Type:  matcher.Object,
+			Value: source.Data,
+		},
+		source: source,
+		opts:   opts,
+	}
+}
+
 // MustMatcher can be used with create matcher functions.  This panics if the create function returns err != nil.
 func MustMatcher(m *Matcher, err error) *Matcher {
 	if err != nil {
@@ -60,9 +87,13 @@ func MustMatcher(m *Matcher, err error) *Matcher {
 	return m
 }
 
-// Match matches a `*datatype.Timestamp`.
+// Match matches a `string` or `[]byte`.  All failures are recorded, see Report.
 func (m *Matcher) Match(actual interface{}) (bool, error) {
+	m.failures = nil
 	if actual == nil {
+		fm := matcher.NewFailureMatcher("", "<object>", "nil")
+		m.curMatcher = fm
+		m.failures = []*matcher.FailureMatcher{fm}
 		return false, nil
 	}
 
@@ -81,13 +112,33 @@ func (m *Matcher) Match(actual interface{}) (bool, error) {
 		Value: bs,
 	}
 
-	mt, matched, err := matcher.Walk("", m.expected, actNode, m.parser)
+	walker := matcher.NewWalker(m.opts.parser)
+	walker.ContinueOnFailure = true
+	walker.StrictObjects = m.opts.strictObjects
+	walker.NumberTolerance = m.opts.tolerance
+	walker.Functions = functionsFor(m.source.Name, m.opts.parser, m.opts.functions)
+	mt, matched, err := walker.Walk("", m.expected, actNode)
 	m.curMatcher = mt
+	m.failures = walker.Failures()
+	if m.source.Name != "" {
+		for _, f := range m.failures {
+			if pos, ok := m.source.Locate(f.Path, m.opts.parser); ok {
+				f.Location = pos.String()
+			}
+		}
+	}
 	return matched, err
 }
 
-// FailureMessage returns failure message.
+// FailureMessage returns failure message.  If there are multiple failures, the messages are joined.
 func (m *Matcher) FailureMessage(actual interface{}) string {
+	if len(m.failures) > 1 {
+		var msgs []string
+		for _, f := range m.failures {
+			msgs = append(msgs, f.FailureMessage(actual))
+		}
+		return fmt.Sprintf("%d failures:\n\n%s", len(m.failures), strings.Join(msgs, "\n\n"))
+	}
 	return m.curMatcher.FailureMessage(actual)
 }
 
@@ -1,9 +1,13 @@
 package gosert
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mina-akimi/gosert/v2/matcher"
+	"github.com/onsi/gomega/matchers"
+	"github.com/onsi/gomega/types"
 )
 
 func TestNewMatcherFromFile(t *testing.T) {
@@ -34,3 +38,92 @@ func TestNewMatcherFromFile(t *testing.T) {
 		t.Fatalf("matched should be true")
 	}
 }
+
+func TestNew_Options(t *testing.T) {
+	clock := func() time.Time {
+		return time.Date(2018, 10, 5, 12, 13, 14, 0, time.UTC)
+	}
+	beUpper := func(args []string) (types.GomegaMatcher, error) {
+		return &matchers.EqualMatcher{Expected: strings.ToUpper(args[0])}, nil
+	}
+	m := MustMatcher(New(
+		[]byte(`{"name": "{{BeUpper(${{NAME}})}}", "age": 36, "updatedAt": "${{@now+1s}}"}`),
+		WithVars(map[string]string{"NAME": "ethan"}),
+		WithNumberTolerance(1),
+		WithClock(clock),
+		WithFunctions(map[string]matcher.Function{"BeUpper": beUpper}),
+	))
+	matched, err := m.Match(`{"name": "ETHAN", "age": 36.5, "updatedAt": "2018-10-05T12:13:15.000Z", "extra": true}`)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if !matched {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+
+	matched, err = m.Match(`{"name": "Ethan", "age": 38, "updatedAt": "2018-10-05T12:13:15.000Z"}`)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	failures := m.Report().Failures
+	if len(failures) != 2 || failures[0].Path != ".age" || failures[1].Path != ".name" {
+		t.Fatalf("report incorrect, %+v", failures)
+	}
+	if !strings.Contains(m.FailureMessage(nil), `to equal`) {
+		t.Fatalf("failure message should contain the function's message, %s", m.FailureMessage(nil))
+	}
+}
+
+func TestNew_StrictObjects(t *testing.T) {
+	m := MustMatcher(New([]byte(`{"foo": "bar", "baz": {"qux": 1}}`), WithStrictObjects(true)))
+	matched, err := m.Match(`{"foo": "bar", "baz": {"qux": 1, "quux": 2}, "extra": true}`)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	failures := m.Report().Failures
+	if len(failures) != 2 || failures[0].Path != ".baz.quux" || failures[1].Path != ".extra" || failures[0].Kind != "unexpected" {
+		t.Fatalf("report incorrect, %+v", failures)
+	}
+}
+
+func TestNew_WithMatcher(t *testing.T) {
+	validOrder := &matchers.HaveKeyWithValueMatcher{
+		Key:   "status",
+		Value: "paid",
+	}
+	m := MustMatcher(New(
+		[]byte(`{"order": "{{Use(validOrder)}}", "items": "{{Not(Use(validOrder))}}"}`),
+		WithMatcher("validOrder", validOrder),
+	))
+	matched, err := m.Match(`{"order": {"id": 1, "status": "paid"}, "items": {"count": 0}}`)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if !matched {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+
+	matched, _ = m.Match(`{"order": {"id": 1, "status": "new"}, "items": {"count": 0}}`)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	msg := m.FailureMessage(nil)
+	if !strings.Contains(msg, "path = .order") || !strings.Contains(msg, "to have {key: value}") {
+		t.Fatalf("failure message incorrect, %s", msg)
+	}
+
+	m = MustMatcher(New([]byte(`{"order": "{{Use(noSuchMatcher)}}"}`), WithMatcher("validOrder", validOrder)))
+	matched, _ = m.Match(`{"order": {}}`)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if r := m.Report(); r.Failures[0].Error != "Use: matcher noSuchMatcher is not defined" {
+		t.Fatalf("error incorrect, %+v", r.Failures)
+	}
+}
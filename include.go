@@ -0,0 +1,204 @@
+package gosert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+var (
+	// A string value that includes another document, e.g. "{{Include(common/address.json)}}"
+	patternInclude = regexp.MustCompile(`"{{Include\(([^()"]*)\)}}"`)
+	// An object that is a reference to another document, e.g. {"$ref": "common/address.json"}
+	patternRef = regexp.MustCompile(`{\s*"\$ref"\s*:\s*"([^"]*)"\s*}`)
+	// A URL scheme, e.g. "https:"
+	patternURLScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]+:`)
+)
+
+// includeError is an error in the include at offset in a document.
+type includeError struct {
+	offset int
+	err    error
+}
+
+// includer replaces includes in golden documents with the included documents.
+type includer struct {
+	vars map[string]string
+	gen  *matcher.Generator
+	// placeholders replaces variables in included documents with placeholders instead of vars, see Validate
+	placeholders bool
+	// stack is the documents being included, to detect cycles
+	stack []string
+}
+
+func newIncluder(vars map[string]string, gen *matcher.Generator) *includer {
+	return &includer{
+		vars: vars,
+		gen:  gen,
+	}
+}
+
+// expand replaces includes in source, in order.  id identifies source for cycle detection, e.g. "golden.txt#key".
+// Paths are relative to the directory of source.Name, or the working directory if source has no name.
+func (in *includer) expand(source *matcher.Source, id string) *includeError {
+	for _, included := range in.stack {
+		if included == id {
+			return &includeError{
+				err: fmt.Errorf("include cycle %s -> %s", strings.Join(in.stack, " -> "), id),
+			}
+		}
+	}
+	in.stack = append(in.stack, id)
+	defer func() {
+		in.stack = in.stack[:len(in.stack)-1]
+	}()
+
+	dir := "."
+	if source.Name != "" {
+		dir = filepath.Dir(source.Name)
+	}
+	offset := 0
+	for {
+		start, end, ref := nextInclude(source.Data, offset)
+		if start < 0 {
+			return nil
+		}
+		path, section := ref, ""
+		if i := strings.Index(ref, "#"); i >= 0 {
+			path, section = ref[:i], ref[i+1:]
+		}
+		data, err := in.load(filepath.Join(dir, path), section)
+		if err != nil {
+			return &includeError{
+				offset: start,
+				err:    fmt.Errorf("cannot include %s: %s", ref, err.Error()),
+			}
+		}
+		source.Splice(start, end, data)
+		offset = start + len(data)
+	}
+}
+
+// nextInclude returns the position of the first include in data after offset, and the reference, e.g.
+// "common/errors.txt#not_found".  Returns -1 if there is none.
+func nextInclude(data []byte, offset int) (int, int, string) {
+	start, end, ref := -1, -1, ""
+	for _, pattern := range []*regexp.Regexp{patternInclude, patternRef} {
+		for from := offset; ; {
+			m := pattern.FindSubmatchIndex(data[from:])
+			if m == nil {
+				break
+			}
+			mStart, mEnd := from+m[0], from+m[1]
+			mRef := strings.TrimSpace(string(data[from+m[2] : from+m[3]]))
+			// Skip escaped quotes, i.e. inside strings, and refs that are not files
+			if (mStart > 0 && data[mStart-1] == '\\') || (pattern == patternRef && !isFileRef(mRef)) {
+				from = mEnd
+				continue
+			}
+			if start < 0 || mStart < start {
+				start, end, ref = mStart, mEnd, mRef
+			}
+			break
+		}
+	}
+	return start, end, ref
+}
+
+// isFileRef returns true if the $ref value ref refers to a file.  Refs within a document (e.g. "#/definitions/user"),
+// URLs and escaped refs (e.g. "{{Literal(common/address.json)}}") are not.
+func isFileRef(ref string) bool {
+	return ref != "" && !strings.HasPrefix(ref, "#") && !patternURLScheme.MatchString(ref) && !strings.HasPrefix(ref, "{{Literal(")
+}
+
+// load returns the JSON document in path, or the section of the multipart file in path, with variables replaced and
+// includes expanded.
+func (in *includer) load(path, section string) ([]byte, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var source *matcher.Source
+	var parser matcher.Parser = matcher.JSONParserInstance
+	if section == "" {
+		source, err = matcher.NewSource(path, bs, nil, in.varsFor(bs), in.gen)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		r, err := newMultipartReader(bytes.NewReader(bs), path, in.varsFor(bs), matcher.JSONParserInstance, in.gen, nil)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if source, ok = r.sources[section]; !ok {
+			return nil, fmt.Errorf("no such key '%s' in file", section)
+		}
+		parser = r.parsers[section]
+	}
+	if ie := in.expand(source, sourceID(path, section)); ie != nil {
+		return nil, ie.err
+	}
+
+	if parser != matcher.JSONParserInstance {
+		// e.g. a section with format=yaml
+		v, err := decodeJSON(matcher.DocumentNode(source.Data), parser)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(v)
+	}
+	// Compact, so that JSON documents can also be included in YAML documents
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, source.Data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// sourceID returns the ID of a document for cycle detection.
+func sourceID(path, section string) string {
+	id := filepath.Clean(path)
+	if section != "" {
+		id += "#" + section
+	}
+	return id
+}
+
+// varsFor returns the variables to replace in the included document data.
+func (in *includer) varsFor(data []byte) map[string]string {
+	if !in.placeholders {
+		return in.vars
+	}
+	vars := map[string]string{}
+	for k, v := range in.vars {
+		vars[k] = v
+	}
+	for _, variable := range matcher.Variables(data) {
+		if _, ok := in.vars[variable.Name]; !ok && !strings.HasPrefix(variable.Name, "@") {
+			setPlaceholder(vars, variable.Name, data, variable.Offset)
+		}
+	}
+	return vars
+}
+
+// newSource returns a new *matcher.Source with variables replaced and includes expanded by inc, if not nil.  id
+// identifies the document for cycle detection, see sourceID.
+func newSource(name, id string, data []byte, lines []int, vars map[string]string, gen *matcher.Generator, inc *includer) (*matcher.Source, error) {
+	source, err := matcher.NewSource(name, data, lines, vars, gen)
+	if err != nil {
+		return nil, err
+	}
+	if inc != nil {
+		if ie := inc.expand(source, id); ie != nil {
+			return nil, fmt.Errorf("%s: %s", source.Position(ie.offset).String(), ie.err.Error())
+		}
+	}
+	return source, nil
+}
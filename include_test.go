@@ -0,0 +1,99 @@
+package gosert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+func TestNewFromFile_Include(t *testing.T) {
+	m, err := NewFromFile("test_asset/include/user.json", WithVars(map[string]string{"STREET": "Main St"}))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	actual := `{
+		"name": "Ethan Hunt",
+		"address": {"street": "Main St", "city": "Langley"},
+		"error": {"error": {"code": 404, "message": "not found"}, "details": "user not found"}
+	}`
+	matched, err := m.Match(actual)
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(actual))
+	}
+
+	// Failures in included documents are located at the include
+	actual = strings.Replace(actual, "Main St", "Side St", 1)
+	matched, _ = m.Match(actual)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if msg := m.FailureMessage(actual); !strings.Contains(msg, "path = .address.street, expected = Main St, actual = Side St, at test_asset/include/user.json:3:14") {
+		t.Fatalf("failure message incorrect\n%s", msg)
+	}
+}
+
+func TestNewFromFile_IncludeErrors(t *testing.T) {
+	_, err := NewFromFile("test_asset/include/cycle_a.json")
+	if err == nil || err.Error() != "test_asset/include/cycle_a.json:2:8: cannot include cycle_b.json: cannot include cycle_a.json: include cycle test_asset/include/cycle_a.json -> test_asset/include/cycle_b.json -> test_asset/include/cycle_a.json" {
+		t.Fatalf("err incorrect: %+v", err)
+	}
+
+	// Variables are passed to included documents
+	_, err = NewFromFile("test_asset/include/user.json")
+	if err == nil || !strings.Contains(err.Error(), "cannot include common/address.json: variable 'STREET' undefined in substitution") {
+		t.Fatalf("err incorrect: %+v", err)
+	}
+
+	_, err = New([]byte(`{"a": {"$ref": "test_asset/include/common/errors.txt#no_such_key"}}`))
+	if err == nil || err.Error() != "1:7: cannot include test_asset/include/common/errors.txt#no_such_key: no such key 'no_such_key' in file" {
+		t.Fatalf("err incorrect: %+v", err)
+	}
+
+	// Refs that are not files, and escaped refs, are not expanded
+	m := MustMatcher(New([]byte(`{"schema": {"$ref": "#/definitions/user"}, "remote": {"$ref": "https://example.com/user.json"}, "file": {"$ref": "{{Literal(common/address.json)}}"}}`)))
+	actual := `{"schema": {"$ref": "#/definitions/user"}, "remote": {"$ref": "https://example.com/user.json"}, "file": {"$ref": "common/address.json"}}`
+	matched, err := m.Match(actual)
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(actual))
+	}
+
+	// Escaped includes are not expanded
+	m = MustMatcher(New([]byte(`{"a": "{{Literal(\"{{Include(x.json)}}\")}}"}`)))
+	matched, err = m.Match(`{"a": "\"{{Include(x.json)}}\""}`)
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+}
+
+func TestMultipartReader_Include(t *testing.T) {
+	r := MustReader(NewMultipartReaderFromFile("test_asset/include/multipart.txt", map[string]string{"STREET": "Main St"}, matcher.JSONParserInstance))
+	m := r.MustGetMatcher("matcher")
+	matched, err := m.Match(r.GetData("fixture"))
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+
+	if err := r.UpdateVars(map[string]string{"STREET": "Side St"}); err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	m = r.MustGetMatcher("matcher")
+	matched, _ = m.Match(r.GetData("fixture"))
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if msg := m.FailureMessage(nil); !strings.Contains(msg, "at test_asset/include/multipart.txt:10:14") {
+		t.Fatalf("failure message incorrect\n%s", msg)
+	}
+}
+
+func TestNew_IncludeOtherFormat(t *testing.T) {
+	m := MustMatcher(New([]byte(`{"a": "{{Include(test_asset/include/multipart.txt#yaml_fixture)}}"}`)))
+	if s := string(m.source.Data); !strings.Contains(s, `"msg":"say \"hi\""`) || !strings.Contains(s, `"id":12345678901234567890`) {
+		t.Fatalf("data incorrect\n%s", s)
+	}
+	matched, err := m.Match(`{"a": {"msg": "say \"hi\"", "id": 12345678901234567890}}`)
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+}
@@ -1,9 +1,11 @@
 package matcher
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,13 +14,45 @@ const (
 	KeyID = "_gst_id"
 	// KeyIndex is used to identify elements by index in an array
 	KeyIndex = "_gst_index"
+	// KeyEmbedded marks an object that is matched with a document embedded in a string, e.g. "_gst_embedded": "json"
+	KeyEmbedded = "_gst_embedded"
+	// KeyValues is an expectation for every value of an object, e.g. for maps keyed by ID
+	KeyValues = "_gst_values"
+	// KeyEachKey is an expectation for the value of every key matching a regex, e.g. "_gst_each_key(^user_\\d+$)"
+	KeyEachKey = "_gst_each_key"
+	// KeyEach is an expectation for every element of an array, e.g. {"_gst_each": {...}}
+	KeyEach = "_gst_each"
+	// KeyDelete lists inherited keys to delete from an object in a section that extends another, e.g. ["address"]
+	KeyDelete = "_gst_delete"
 )
 
 var (
 	// JSONParserInstance is a singleton.
 	JSONParserInstance = &JSONParser{}
+
+	parsersMu sync.RWMutex
+	parsers   = map[string]Parser{
+		"json": JSONParserInstance,
+		"yaml": YAMLParserInstance,
+	}
 )
 
+// RegisterParser registers parser with name, e.g. so that it can be selected in multipart headers with parser=name.
+// "json" and "yaml" are registered by default.  Registering a name again replaces the parser.
+func RegisterParser(name string, parser Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[name] = parser
+}
+
+// LookupParser returns the registered parser with name.
+func LookupParser(name string) (Parser, bool) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	p, ok := parsers[name]
+	return p, ok
+}
+
 // ValueType defines value types available.
 type ValueType int
 
@@ -61,6 +95,9 @@ func (t ValueType) String() string {
 type Node struct {
 	Type  ValueType
 	Value []byte
+	// Offset is the offset of Value in the data passed to GetFields or GetArray.  It is only set by parsers that
+	// implement Locator.
+	Offset int
 }
 
 // String returns string.
@@ -68,6 +105,23 @@ func (n Node) String() string {
 	return fmt.Sprintf("type = %s, value = %s", n.Type.String(), string(n.Value))
 }
 
+// Raw returns n encoded as it appears in a document, i.e. strings are quoted.
+func (n Node) Raw() []byte {
+	if n.Type == String {
+		return []byte(`"` + string(n.Value) + `"`)
+	}
+	return n.Value
+}
+
+// Raw returns ns encoded as an array.
+func (ns Nodes) Raw() []byte {
+	var raws [][]byte
+	for _, node := range ns {
+		raws = append(raws, node.Raw())
+	}
+	return []byte("[" + string(bytes.Join(raws, []byte(","))) + "]")
+}
+
 // String returns string.
 func (ns Nodes) String() string {
 	var strs []string
@@ -120,6 +174,39 @@ func IsBaseTypes(nodes []Node) bool {
 	return true
 }
 
+// Decode returns node as a Go value: string, float64, bool, nil, map[string]interface{} or []interface{}.
+func Decode(node Node, parser Parser) interface{} {
+	switch node.Type {
+	case String:
+		return string(node.Value)
+	case Number:
+		f, err := toNumber(node.Value)
+		if err != nil {
+			return string(node.Value)
+		}
+		return f
+	case Boolean:
+		b, err := toBool(node.Value)
+		if err != nil {
+			return string(node.Value)
+		}
+		return b
+	case Object:
+		m := map[string]interface{}{}
+		for k, v := range parser.GetFields(node.Value) {
+			m[k] = Decode(v, parser)
+		}
+		return m
+	case Array:
+		a := []interface{}{}
+		for _, v := range parser.GetArray(node.Value) {
+			a = append(a, Decode(v, parser))
+		}
+		return a
+	}
+	return nil
+}
+
 func toNumber(input []byte) (float64, error) {
 	s := string(input)
 	return strconv.ParseFloat(s, 64)
@@ -0,0 +1,217 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorCyan   = "\x1b[36m"
+	colorYellow = "\x1b[1;33m"
+
+	diffContext = 3
+)
+
+var (
+	// Colorize enables ANSI colors in failure messages.  It defaults to true if stdout is a terminal.
+	Colorize = isTerminal(os.Stdout)
+	// MaxDiffLines is the maximum number of lines of a value shown in a diff.  Longer values are truncated.
+	MaxDiffLines = 100
+	// MaxDiffLineLength is the maximum length of a line shown in a diff.  Longer lines are truncated.
+	MaxDiffLineLength = 200
+)
+
+// Diff returns a unified diff of the pretty-printed expected and actual values at path.
+func Diff(path, expected, actual string) string {
+	exp := prettyLines(expected)
+	act := prettyLines(actual)
+
+	var buf bytes.Buffer
+	buf.WriteString(colorize(colorYellow, "@ "+displayPath(path)) + "\n")
+	buf.WriteString(colorize(colorRed, "--- expected") + "\n")
+	buf.WriteString(colorize(colorGreen, "+++ actual") + "\n")
+	for _, h := range diffHunks(diffLines(exp, act)) {
+		buf.WriteString(colorize(colorCyan, fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.expStart+1, h.expLen, h.actStart+1, h.actLen)) + "\n")
+		for _, l := range h.lines {
+			switch l.op {
+			case '-':
+				buf.WriteString(colorize(colorRed, "-"+l.text) + "\n")
+			case '+':
+				buf.WriteString(colorize(colorGreen, "+"+l.text) + "\n")
+			default:
+				buf.WriteString(" " + l.text + "\n")
+			}
+		}
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "<root>"
+	}
+	return path
+}
+
+func colorize(color, s string) string {
+	if !Colorize {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func isTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// prettyLines returns s indented (if it is a JSON document) and split into truncated lines.
+func prettyLines(s string) []string {
+	var buf bytes.Buffer
+	if json.Valid([]byte(s)) && json.Indent(&buf, []byte(s), "", "  ") == nil {
+		s = buf.String()
+	}
+	lines := strings.Split(s, "\n")
+	var result []string
+	for i, l := range lines {
+		if i >= MaxDiffLines {
+			result = append(result, fmt.Sprintf("... (%d more lines)", len(lines)-i))
+			break
+		}
+		result = append(result, truncate(l, MaxDiffLineLength))
+	}
+	return result
+}
+
+// truncate cuts s to at most n bytes, on a rune boundary so that multi-byte characters are not split.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+type diffHunk struct {
+	expStart, expLen int
+	actStart, actLen int
+	lines            []diffLine
+}
+
+// diffLines returns the line diff of exp and act, based on their longest common subsequence.
+func diffLines(exp, act []string) []diffLine {
+	n, m := len(exp), len(act)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if exp[i] == act[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var result []diffLine
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && exp[i] == act[j]:
+			result = append(result, diffLine{op: ' ', text: exp[i]})
+			i++
+			j++
+		case j >= m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			result = append(result, diffLine{op: '-', text: exp[i]})
+			i++
+		default:
+			result = append(result, diffLine{op: '+', text: act[j]})
+			j++
+		}
+	}
+	return result
+}
+
+// diffHunks groups lines into hunks, keeping diffContext unchanged lines around changes.
+func diffHunks(lines []diffLine) []diffHunk {
+	var hunks []diffHunk
+	var cur *diffHunk
+	lastChange := -1
+	appendContext := func(from, to int) {
+		for k := from; k < to && k < len(lines); k++ {
+			cur.lines = append(cur.lines, lines[k])
+			cur.expLen++
+			cur.actLen++
+		}
+	}
+	expLine, actLine := 0, 0
+	for i, l := range lines {
+		if l.op != ' ' {
+			if cur == nil || i-lastChange > 2*diffContext {
+				if cur != nil {
+					appendContext(lastChange+1, lastChange+1+diffContext)
+					hunks = append(hunks, *cur)
+				}
+				start := i - diffContext
+				if start < lastChange+1 {
+					start = lastChange + 1
+				}
+				if start < 0 {
+					start = 0
+				}
+				cur = &diffHunk{
+					expStart: expLine - (i - start),
+					actStart: actLine - (i - start),
+				}
+				appendContext(start, i)
+			} else {
+				appendContext(lastChange+1, i)
+			}
+			cur.lines = append(cur.lines, l)
+			if l.op == '-' {
+				cur.expLen++
+			} else {
+				cur.actLen++
+			}
+			lastChange = i
+		}
+		switch l.op {
+		case '-':
+			expLine++
+		case '+':
+			actLine++
+		default:
+			expLine++
+			actLine++
+		}
+	}
+	if cur != nil {
+		appendContext(lastChange+1, lastChange+1+diffContext)
+		hunks = append(hunks, *cur)
+	}
+	return hunks
+}
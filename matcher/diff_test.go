@@ -0,0 +1,89 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// setColorize sets Colorize for the duration of the test.
+func setColorize(t *testing.T, colorize bool) {
+	old := Colorize
+	Colorize = colorize
+	t.Cleanup(func() {
+		Colorize = old
+	})
+}
+
+func TestDiff(t *testing.T) {
+	setColorize(t, false)
+	exp := `{"a": 1, "b": {"c": "foo", "d": [1, 2]}}`
+	act := `{"a": 1, "b": {"c": "bar", "d": [1, 2]}}`
+
+	diff := Diff(".b", exp, act)
+	expected := `@ .b
+--- expected
++++ actual
+@@ -1,7 +1,7 @@
+ {
+   "a": 1,
+   "b": {
+-    "c": "foo",
++    "c": "bar",
+     "d": [
+       1,
+       2`
+	if diff != expected {
+		t.Fatalf("Diff() result incorrect, %s", diff)
+	}
+}
+
+func TestDiff_Color(t *testing.T) {
+	setColorize(t, true)
+
+	diff := Diff("", "foo", "bar")
+	if !strings.Contains(diff, colorRed+"-foo"+colorReset) || !strings.Contains(diff, colorGreen+"+bar"+colorReset) {
+		t.Fatalf("Diff() should be colored, %q", diff)
+	}
+}
+
+func TestDiff_Truncate(t *testing.T) {
+	setColorize(t, false)
+	var exp, act []string
+	for i := 0; i < MaxDiffLines*2; i++ {
+		exp = append(exp, "line")
+		act = append(act, "line")
+	}
+	act[0] = strings.Repeat("x", MaxDiffLineLength*2)
+
+	diff := Diff(".a", strings.Join(exp, "\n"), strings.Join(act, "\n"))
+	if strings.Contains(diff, strings.Repeat("x", MaxDiffLineLength+1)) {
+		t.Fatalf("Diff() should truncate long lines, %s", diff)
+	}
+	lines := prettyLines(strings.Join(exp, "\n"))
+	if len(lines) != MaxDiffLines+1 || lines[MaxDiffLines] != "... (100 more lines)" {
+		t.Fatalf("prettyLines() should truncate long values, %+v", lines)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	// "é" is 2 bytes, cutting at 3 bytes would split the second one
+	if s := truncate("éééé", 3); s != "é..." || !utf8.ValidString(s) {
+		t.Fatalf("truncate() should cut on a rune boundary, %q", s)
+	}
+	if s := truncate("abc", 3); s != "abc" {
+		t.Fatalf("truncate() should not cut short strings, %q", s)
+	}
+}
+
+func TestFailureMatcher_FailureMessage(t *testing.T) {
+	setColorize(t, false)
+	matcher := NewFailureMatcher(".a", `{"b": 1}`, `{"b": 2}`)
+	msg := matcher.FailureMessage(nil)
+	if !strings.HasPrefix(msg, `path = .a, expected = {"b": 1}, actual = {"b": 2}`) {
+		t.Fatalf("FailureMessage() should start with summary, %s", msg)
+	}
+	if !strings.Contains(msg, "-  \"b\": 1\n+  \"b\": 2") {
+		t.Fatalf("FailureMessage() should contain diff, %s", msg)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -13,256 +14,566 @@ import (
 )
 
 var (
-	// Usage: {{ts(2018-01-02T12:13:14.123Z, 5000)}}, which means 2018-01-02T12:13:14.123Z +/- 5000 milliseconds
-	patternTimestamp = regexp.MustCompile(`^{{BeTimestamp\((?P<time>\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}.\d{3}Z),\s*(?P<delta>\d+)\)}}$`)
-	// Usage: {{num(123.23, 0.5)}}, which means 123.23 +/- 0.5
-	patternNumber = regexp.MustCompile(`^{{BeNumerically\((?P<comparator>[^,]+),\s*(?P<data>[^)]+)\)}}$`)
 	// Usage: {{BeEmpty()}}, which means the string/array must be empty
 	patternEmpty = regexp.MustCompile(`^{{BeEmpty\(\)}}$`)
 	// Usage: {{Not(BeEmpty())}}, which means the string/array must not be empty
 	patternNotEmpty = regexp.MustCompile(`^{{Not\(BeEmpty\(\)\)}}$`)
-	// Usage: ${{MY_VAR}}, which can be replaced with a value
-	patternVariable = regexp.MustCompile(`\${{(?P<var>\w+)}}`)
+	// Usage: ${{MY_VAR}}, which can be replaced with a value, or ${{@uuid}}, which is evaluated by a Generator.
+	// $${{MY_VAR}} is escaped and becomes the literal text ${{MY_VAR}}.
+	patternSubstitution = regexp.MustCompile(`\$(?P<escape>\$)?{{(?P<var>@[^}]+|\w+)}}`)
+	// Usage: {{MyFunction(arg0, arg1)}}, which calls a function (see RegisterFunction)
+	patternFunction = regexp.MustCompile(`(?s)^{{(?P<name>\w+)\((?P<args>.*)\)}}$`)
+	// Usage: {{Literal({{BeEmpty()}})}}, which means the string must be exactly {{BeEmpty()}}
+	patternLiteral = regexp.MustCompile(`(?s)^{{Literal\((?P<text>.*)\)}}$`)
+	// Matches {{Literal(...)}} inside a document, stopping at the end of the enclosing string or line.  In YAML
+	// single-quoted strings, the quotes are part of the match and '' is an escaped quote.
+	patternLiteralSpan = regexp.MustCompile(`'{{Literal\((?:[^'\n]|'')*\)}}'|{{Literal\((?:[^"\\\n]|\\.)*\)}}`)
 
-	arrayPatterns = []string{"^{{Not(BeEmpty())}}$"}
+	// Usage: "_gst_each_key(^user_\\d+$)": {...}, which matches the value of every key matching the regex
+	patternEachKey = regexp.MustCompile(`^` + KeyEachKey + `\((?P<regex>.*)\)$`)
+
+	// Usage: {{Each({{Not(BeEmpty())}})}}, which matches every element of an array
+	patternEach = regexp.MustCompile(`(?s)^{{Each\((?P<nested>.*)\)}}$`)
+
+	arrayPatterns = []string{"^{{BeEmpty()}}$", "^{{Not(BeEmpty())}}$", "^{{Each(...)}}$"}
 )
 
 // ===========
 // Tree walker
 // ===========
 
+// DefaultNumberTolerance is the tolerance used when comparing an actual number with an expected number.
+const DefaultNumberTolerance = 0.05
+
+// Function creates a matcher from the arguments of a function call in a golden file, e.g. {{MyFunction(a, b)}}.  The
+// matcher receives the decoded actual value (see Decode).
+type Function func(args []string) (types.GomegaMatcher, error)
+
+// Walker walks an expected tree and an actual tree, matching elements in the actual tree with the expected tree.
+type Walker struct {
+	Parser Parser
+	// ContinueOnFailure makes the walker check the rest of the tree after a failure.  All failures are recorded and
+	// can be retrieved with Failures.
+	ContinueOnFailure bool
+	// StrictObjects makes actual objects fail if they have fields that are not expected.
+	StrictObjects bool
+	// NumberTolerance is the tolerance used when comparing an actual number with an expected number.
+	NumberTolerance float64
+	// Functions are custom functions that can be used in the golden file, by name.
+	Functions map[string]Function
+
+	failures []*FailureMatcher
+}
+
+// NewWalker returns a new *Walker.
+func NewWalker(parser Parser) *Walker {
+	return &Walker{
+		Parser:          parser,
+		NumberTolerance: DefaultNumberTolerance,
+	}
+}
+
+// Failures returns the failures recorded so far, in the order they were found.
+func (w *Walker) Failures() []*FailureMatcher {
+	return w.failures
+}
+
+// fail records m as a failure of kind.  If err is not nil, the kind is FailureError.
+func (w *Walker) fail(m *FailureMatcher, kind FailureKind, err error) (types.GomegaMatcher, bool, error) {
+	m.Kind = kind
+	m.Err = err
+	if err != nil {
+		m.Kind = FailureError
+	}
+	w.failures = append(w.failures, m)
+	return m, false, err
+}
+
 // Walk recursively iterates the tree structure, matching elements in act with exp.
 func Walk(path string, exp, act Node, parser Parser) (types.GomegaMatcher, bool, error) {
+	return NewWalker(parser).Walk(path, exp, act)
+}
+
+// Walk recursively iterates the tree structure, matching elements in act with exp.
+func (w *Walker) Walk(path string, exp, act Node) (types.GomegaMatcher, bool, error) {
+	if isDecoderCall(exp) {
+		return w.walkDecoded(path, exp, act)
+	}
+	if matcher, ok, err := w.createFunctionMatcher(exp); ok {
+		if err != nil {
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Raw())), FailureMismatch, err)
+		}
+		actual := Decode(act, w.Parser)
+		var matched bool
+		if nm, ok := matcher.(NodeMatcher); ok {
+			matched, err = nm.MatchNode(path, act, w.Parser)
+		} else {
+			matched, err = matcher.Match(actual)
+		}
+		if !matched || err != nil {
+			fm := NewFailureMatcher(path, string(exp.Value), string(act.Raw()))
+			if err == nil {
+				fm.Reason = matcher.FailureMessage(actual)
+			}
+			return w.fail(fm, FailureMismatch, err)
+		}
+		return SuccessMatcherInstance, true, nil
+	}
+
 	switch act.Type {
 	case String:
+		if exp.Type == Object && isEmbedded(exp, w.Parser) {
+			return w.walkEmbedded(path, exp, act)
+		}
 		if exp.Type != String {
-			return NewFailureMatcher(path, exp.Type.String(), act.Type.String()), false, fmt.Errorf("path has type String but assertion uses %s", exp.Type.String())
+			return w.fail(NewFailureMatcher(path, exp.Type.String(), act.Type.String()), FailureType, fmt.Errorf("path has type String but assertion uses %s", exp.Type.String()))
 		}
 		matcher, err := CreateStringMatcher(string(exp.Value))
 		if err != nil {
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 		}
 		matched, err := matcher.Match(string(act.Value))
 		if !matched || err != nil {
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), matched, err
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 		}
 	case Number:
 		actVal, err := toNumber(act.Value)
 		if err != nil {
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 		}
 		switch exp.Type {
 		case String:
 			matcher, err := CreateNumberMatcher(string(exp.Value))
 			if err != nil {
-				return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+				return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 			}
 			matched, err := matcher.Match(actVal)
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), matched, err
+			if !matched || err != nil {
+				return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
+			}
 		case Number:
 			expVal, err := toNumber(exp.Value)
 			if err != nil {
-				return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+				return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 			}
 			matcher := &matchers.BeNumericallyMatcher{
 				Comparator: "~",
-				CompareTo:  []interface{}{expVal, 0.05},
+				CompareTo:  []interface{}{expVal, w.NumberTolerance},
 			}
 			matched, err := matcher.Match(actVal)
 			if !matched || err != nil {
-				return NewFailureMatcher(path, string(exp.Value), string(act.Value)), matched, err
+				return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 			}
 		default:
-			return NewFailureMatcher(path, exp.Type.String(), act.Type.String()), false, fmt.Errorf("path has type Number but assertion uses %s.  Allowed types are String or Number.", exp.Type.String())
+			return w.fail(NewFailureMatcher(path, exp.Type.String(), act.Type.String()), FailureType, fmt.Errorf("path has type Number but assertion uses %s.  Allowed types are String or Number.", exp.Type.String()))
 		}
 	case Boolean:
 		if exp.Type != Boolean {
-			return NewFailureMatcher(path, exp.Type.String(), act.Type.String()), false, fmt.Errorf("path has type Boolean but assertion uses %s", exp.Type.String())
+			return w.fail(NewFailureMatcher(path, exp.Type.String(), act.Type.String()), FailureType, fmt.Errorf("path has type Boolean but assertion uses %s", exp.Type.String()))
 		}
 		expBool, err := toBool(exp.Value)
 		if err != nil {
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 		}
 		actBool, err := toBool(act.Value)
 		if err != nil {
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 		}
 		if expBool != actBool {
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, nil)
 		}
 	case Array:
 		switch exp.Type {
 		case Array:
-			return MatchArrayWithArray(path, parser.GetArray(exp.Value), parser.GetArray(act.Value), parser)
+			return w.MatchArrayWithArray(path, w.Parser.GetArray(exp.Value), w.Parser.GetArray(act.Value))
 		case String:
-			return MatchArrayWithString(path, exp, parser.GetArray(act.Value))
+			return w.MatchArrayWithString(path, exp, w.Parser.GetArray(act.Value))
 		default:
-			return NewFailureMatcher(path, exp.Type.String(), act.Type.String()), false, fmt.Errorf("unsupported expected value type '%s' for Array type.  See Gosert doc.", exp.Type.String())
+			return w.fail(NewFailureMatcher(path, exp.Type.String(), act.Type.String()), FailureType, fmt.Errorf("unsupported expected value type '%s' for Array type.  See Gosert doc.", exp.Type.String()))
 		}
 	case Object:
-		err := parser.ValidateObject(act.Value)
+		if exp.Type != Object {
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Raw())), FailureType, fmt.Errorf("path has type Object but assertion uses %s", exp.Type.String()))
+		}
+		err := w.Parser.ValidateObject(act.Value)
+		if err != nil {
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
+		}
+		err = w.Parser.ValidateObject(exp.Value)
 		if err != nil {
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 		}
-		err = parser.ValidateObject(exp.Value)
+		actObj := w.Parser.GetFields(act.Value)
+		expObj := w.Parser.GetFields(exp.Value)
+		values, hasValues := expObj[KeyValues]
+		delete(expObj, KeyValues)
+		eachKeys, err := createEachKeyExpectations(expObj)
 		if err != nil {
-			return NewFailureMatcher(path, string(exp.Value), string(act.Value)), false, err
+			return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
 		}
-		actObj := parser.GetFields(act.Value)
-		expObj := parser.GetFields(exp.Value)
-		for k, v := range expObj {
+		var first types.GomegaMatcher
+		var firstErr error
+		for _, k := range sortedKeys(expObj) {
+			v := expObj[k]
+			var matcher types.GomegaMatcher
+			var matched bool
+			var err error
 			if a, ok := actObj[k]; !ok {
 				if isBeEmpty(v) {
 					continue
 				}
-				return NewFailureMatcher(path+"."+k, string(v.Value), string(a.Value)), false, nil
+				matcher, matched, err = w.fail(NewFailureMatcher(path+"."+k, string(v.Value), string(a.Value)), FailureMissing, nil)
 			} else {
 				// Recursion
-				matcher, matched, err := Walk(path+"."+k, v, a, parser)
-				if !matched || err != nil {
+				matcher, matched, err = w.Walk(path+"."+k, v, a)
+			}
+			if !matched || err != nil {
+				if !w.ContinueOnFailure {
 					return matcher, matched, err
 				}
+				if first == nil {
+					first, firstErr = matcher, err
+				}
+			}
+		}
+		// Keys that are not in the golden value are matched with KeyEachKey and KeyValues expectations
+		for _, k := range sortedKeys(actObj) {
+			if _, ok := expObj[k]; ok {
+				continue
+			}
+			exps := eachKeys.find(k)
+			if len(exps) == 0 && hasValues {
+				exps = []Node{values}
+			}
+			if len(exps) == 0 && w.StrictObjects {
+				matcher, _, err := w.fail(NewFailureMatcher(path+"."+k, "", string(actObj[k].Raw())), FailureUnexpected, nil)
+				if !w.ContinueOnFailure {
+					return matcher, false, err
+				}
+				if first == nil {
+					first, firstErr = matcher, err
+				}
+			}
+			for _, v := range exps {
+				matcher, matched, err := w.Walk(path+"."+k, v, actObj[k])
+				if !matched || err != nil {
+					if !w.ContinueOnFailure {
+						return matcher, matched, err
+					}
+					if first == nil {
+						first, firstErr = matcher, err
+					}
+				}
 			}
 		}
+		if first != nil {
+			return first, false, firstErr
+		}
 	}
 	return SuccessMatcherInstance, true, nil
 }
 
+// createFunctionMatcher returns a matcher if exp calls a registered function or one of w.Functions.  Returns false if
+// it doesn't.
+func (w *Walker) createFunctionMatcher(exp Node) (types.GomegaMatcher, bool, error) {
+	if exp.Type != String {
+		return nil, false, nil
+	}
+	return CreateFunctionMatcher(string(exp.Value), w.Functions)
+}
+
 // MatchArrayWithArray matches act with exp as plain arrays.
 func MatchArrayWithArray(path string, exp, act []Node, parser Parser) (types.GomegaMatcher, bool, error) {
+	return NewWalker(parser).MatchArrayWithArray(path, exp, act)
+}
+
+// MatchArrayWithArray matches act with exp as plain arrays.
+func (w *Walker) MatchArrayWithArray(path string, exp, act []Node) (types.GomegaMatcher, bool, error) {
+	parser := w.Parser
+	each, exp := splitEachExpectations(exp, parser)
+	if len(each) > 0 {
+		matcher, matched, err := w.matchEach(path, each, act)
+		if len(exp) == 0 || (!w.ContinueOnFailure && (!matched || err != nil)) {
+			return matcher, matched, err
+		}
+		if !matched || err != nil {
+			// Collect the failures of the other expected elements, but return the first failure
+			w.MatchArrayWithArray(path, exp, act)
+			return matcher, matched, err
+		}
+	}
 	if IsBaseTypes(exp) {
 		if !IsBaseTypes(act) {
-			return NewFailureMatcher(path, Nodes(exp).String(), Nodes(act).String()), false, fmt.Errorf("array should contain base type only but got object type")
+			return w.fail(NewFailureMatcher(path, string(Nodes(exp).Raw()), string(Nodes(act).Raw())), FailureType, fmt.Errorf("array should contain base type only but got object type"))
 		}
 		if !baseNodesEqual(exp, act) {
-			return NewFailureMatcher(path, Nodes(exp).String(), Nodes(act).String()), false, nil
+			return w.fail(NewFailureMatcher(path, string(Nodes(exp).Raw()), string(Nodes(act).Raw())), FailureMismatch, nil)
 		}
 		return SuccessMatcherInstance, true, nil
 	} else if IsObjects(exp) {
 		isByIndex, err := isArrayExpectedByIndex(exp, parser)
 		if err != nil {
-			return NewFailureMatcher(path, Nodes(exp).String(), Nodes(act).String()), false, err
+			return w.fail(NewFailureMatcher(path, string(Nodes(exp).Raw()), string(Nodes(act).Raw())), FailureMismatch, err)
 		}
+		var first types.GomegaMatcher
+		var firstErr error
 		if isByIndex { // Expected by index
 			expMap, err := createExpectedIndexMapper(exp, parser)
 			if err != nil {
-				return NewFailureMatcher(path, Nodes(exp).String(), Nodes(act).String()), false, err
+				return w.fail(NewFailureMatcher(path, string(Nodes(exp).Raw()), string(Nodes(act).Raw())), FailureMismatch, err)
 			}
 			for i, a := range act {
 				if e, ok := expMap[i]; ok {
 					// Recursion
-					matcher, matched, err := Walk(path+"["+strconv.Itoa(i)+"]", e, a, parser)
+					matcher, matched, err := w.Walk(path+"["+strconv.Itoa(i)+"]", e, a)
 					if !matched || err != nil {
-						return matcher, matched, err
+						if !w.ContinueOnFailure {
+							return matcher, matched, err
+						}
+						if first == nil {
+							first, firstErr = matcher, err
+						}
 					}
 				}
 			}
-			return SuccessMatcherInstance, true, nil
 		} else { // Expected by ID
 			metaKey, expMap, err := createExpectedIDMapper(exp, parser)
 			if err != nil {
-				return NewFailureMatcher(path, Nodes(exp).String(), Nodes(act).String()), false, err
+				return w.fail(NewFailureMatcher(path, string(Nodes(exp).Raw()), string(Nodes(act).Raw())), FailureMismatch, err)
 			}
 			actMap, err := createActualIDMapper(act, metaKey, parser)
 			if err != nil {
-				return NewFailureMatcher(path, Nodes(exp).String(), Nodes(act).String()), false, err
+				return w.fail(NewFailureMatcher(path, string(Nodes(exp).Raw()), string(Nodes(act).Raw())), FailureMismatch, err)
 			}
-			for k, e := range expMap {
-				a, ok := actMap[k]
-				if !ok {
-					return NewFailureMatcher(path+"."+metaKey+"="+k, Nodes(exp).String(), Nodes(act).String()), false, nil
+			for _, k := range sortedKeys(expMap) {
+				e := expMap[k]
+				var matcher types.GomegaMatcher
+				var matched bool
+				var err error
+				if a, ok := actMap[k]; !ok {
+					matcher, matched, err = w.fail(NewFailureMatcher(path+"."+metaKey+"="+k, string(e.Value), ""), FailureMissing, nil)
+				} else {
+					// Recursion
+					matcher, matched, err = w.Walk(path+"."+metaKey+"="+k, e, a)
 				}
-				// Recursion
-				matcher, matched, err := Walk(path+"."+metaKey+"="+k, e, a, parser)
 				if !matched || err != nil {
-					return matcher, matched, err
+					if !w.ContinueOnFailure {
+						return matcher, matched, err
+					}
+					if first == nil {
+						first, firstErr = matcher, err
+					}
 				}
 			}
-			return SuccessMatcherInstance, true, nil
 		}
+		if first != nil {
+			return first, false, firstErr
+		}
+		return SuccessMatcherInstance, true, nil
 	} else {
-		return NewFailureMatcher(path, Nodes(exp).String(), Nodes(act).String()), false, fmt.Errorf("array type assertion must all be base data type or all object type, not a mixture of both")
+		return w.fail(NewFailureMatcher(path, string(Nodes(exp).Raw()), string(Nodes(act).Raw())), FailureType, fmt.Errorf("array type assertion must all be base data type or all object type, not a mixture of both"))
+	}
+}
+
+// splitEachExpectations returns the expectations of elements like {"_gst_each": ...}, and the other elements.
+func splitEachExpectations(exp []Node, parser Parser) ([]Node, []Node) {
+	var each, rest []Node
+	for _, e := range exp {
+		if e.Type == Object {
+			fields := parser.GetFields(e.Value)
+			if v, ok := fields[KeyEach]; ok && len(fields) == 1 {
+				each = append(each, v)
+				continue
+			}
+		}
+		rest = append(rest, e)
 	}
+	return each, rest
 }
 
-// MatchArrayWithArray matches act with exp.  exp must be a function.
+// eachExpectation returns the expectation nested in {{Each(...)}}.  Like embedded documents, nested text starting with
+// "{" or "[" is an object or an array, e.g. {{Each({\"id\": \"{{Not(BeEmpty())}}\"})}}.
+func (w *Walker) eachExpectation(nested []byte) Node {
+	unescaped, err := unescape(Node{Type: String, Value: nested}, w.Parser)
+	if err != nil {
+		return Node{Type: String, Value: nested}
+	}
+	trimmed := bytes.TrimSpace(unescaped)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return Node{Type: Array, Value: trimmed}
+	case bytes.HasPrefix(trimmed, []byte("{")) && !bytes.HasPrefix(trimmed, []byte("{{")):
+		return Node{Type: Object, Value: trimmed}
+	}
+	return Node{Type: String, Value: nested}
+}
+
+// matchEach matches every element of act with every expectation in each.
+func (w *Walker) matchEach(path string, each, act []Node) (types.GomegaMatcher, bool, error) {
+	var first types.GomegaMatcher
+	var firstErr error
+	for i, a := range act {
+		for _, e := range each {
+			// Recursion
+			matcher, matched, err := w.Walk(path+"["+strconv.Itoa(i)+"]", e, a)
+			if !matched || err != nil {
+				if !w.ContinueOnFailure {
+					return matcher, matched, err
+				}
+				if first == nil {
+					first, firstErr = matcher, err
+				}
+			}
+		}
+	}
+	if first != nil {
+		return first, false, firstErr
+	}
+	return SuccessMatcherInstance, true, nil
+}
+
+// MatchArrayWithString matches act with exp.  exp must be a function.
 func MatchArrayWithString(path string, exp Node, act []Node) (types.GomegaMatcher, bool, error) {
+	return (&Walker{}).MatchArrayWithString(path, exp, act)
+}
+
+// MatchArrayWithString matches act with exp.  exp must be a function.
+func (w *Walker) MatchArrayWithString(path string, exp Node, act []Node) (types.GomegaMatcher, bool, error) {
 	if exp.Type != String {
-		return NewFailureMatcher(path, exp.String(), Nodes(act).String()), false, fmt.Errorf("array type assertion cannot use type '%s' as expected value", exp.Type.String())
+		return w.fail(NewFailureMatcher(path, string(exp.Raw()), string(Nodes(act).Raw())), FailureType, fmt.Errorf("array type assertion cannot use type '%s' as expected value", exp.Type.String()))
 	}
 	v := string(exp.Value)
+	if isLiteral(exp) {
+		return w.fail(NewFailureMatcher(path, string(exp.Raw()), string(Nodes(act).Raw())), FailureType, fmt.Errorf("path has type Array but assertion is a literal string"))
+	}
+	if m := patternEach.FindStringSubmatch(v); m != nil {
+		return w.matchEach(path, []Node{w.eachExpectation([]byte(m[1]))}, act)
+	}
 	if patternEmpty.MatchString(v) {
 		if len(act) > 0 {
-			return NewFailureMatcher(path, exp.String(), Nodes(act).String()), false, nil
+			return w.fail(NewFailureMatcher(path, string(exp.Raw()), string(Nodes(act).Raw())), FailureMismatch, nil)
 		}
 		return SuccessMatcherInstance, true, nil
 	}
 	if patternNotEmpty.MatchString(v) {
 		if len(act) <= 0 {
-			return NewFailureMatcher(path, exp.String(), Nodes(act).String()), false, nil
+			return w.fail(NewFailureMatcher(path, string(exp.Raw()), string(Nodes(act).Raw())), FailureMismatch, nil)
 		}
 		return SuccessMatcherInstance, true, nil
 	}
-	return NewFailureMatcher(path, exp.String(), Nodes(act).String()), false, fmt.Errorf("array type assertion can only use functions %+v", arrayPatterns)
+	return w.fail(NewFailureMatcher(path, string(exp.Raw()), string(Nodes(act).Raw())), FailureMismatch, fmt.Errorf("array type assertion can only use functions %+v", arrayPatterns))
 }
 
 // CreateNumberMatcher returns a matcher for numbers.  input must be a function.
 func CreateNumberMatcher(input string) (types.GomegaMatcher, error) {
-	if patternNumber.MatchString(input) {
-		m := patternNumber.FindStringSubmatch(input)
-		sub := mapSubexpNames(m, patternNumber.SubexpNames())
-		comparator := sub["comparator"]
-		data := sub["data"]
-		datas := strings.Split(data, ",")
-		var flts []interface{}
-		for _, d := range datas {
-			d = strings.TrimSpace(d)
-			dflt, err := strconv.ParseFloat(d, 64)
-			if err != nil {
-				return nil, err
-			}
-			flts = append(flts, dflt)
-		}
-		return &matchers.BeNumericallyMatcher{
-			Comparator: comparator,
-			CompareTo:  flts,
-		}, nil
+	matcher, ok, err := CreateFunctionMatcher(input, nil)
+	if ok {
+		return matcher, err
 	}
 	return nil, fmt.Errorf("path has type Number but assertion ('%s') does not have the correct format.  Must be {{BeNumerically(...)}}.  See Gosert doc.", input)
 }
 
 // CreateStringMatcher returns a matcher for string.  input must be either a plain string or a function.
 func CreateStringMatcher(input string) (types.GomegaMatcher, error) {
-	if patternEmpty.MatchString(input) {
-		return &matchers.BeEmptyMatcher{}, nil
-	}
-	if patternNotEmpty.MatchString(input) {
-		return &matchers.NotMatcher{
-			Matcher: &matchers.BeEmptyMatcher{},
+	if patternLiteral.MatchString(input) {
+		return &matchers.EqualMatcher{
+			Expected: literalText(input),
 		}, nil
 	}
-	if patternTimestamp.MatchString(input) {
-		m := patternTimestamp.FindStringSubmatch(input)
-		sub := mapSubexpNames(m, patternTimestamp.SubexpNames())
-		tsStr := sub["time"]
-		deltaStr := sub["delta"]
-		ts, err := ParseTime(tsStr)
-		if err != nil {
-			return nil, err
-		}
-		delta, err := strconv.ParseInt(deltaStr, 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		return NewTimestampMatcher(ts, time.Duration(delta)*time.Millisecond), nil
+	matcher, ok, err := CreateFunctionMatcher(input, nil)
+	if ok {
+		return matcher, err
 	}
 	return &matchers.EqualMatcher{
 		Expected: input,
 	}, nil
 }
 
+// splitArgs splits function arguments by commas, ignoring commas inside nested brackets.
+func splitArgs(input string) []string {
+	if strings.TrimSpace(input) == "" {
+		return nil
+	}
+	var args []string
+	depth := 0
+	start := 0
+	for i, c := range input {
+		switch c {
+		case '(', '{', '[':
+			depth++
+		case ')', '}', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(input[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(input[start:]))
+}
+
+// eachKeyExpectation is the expectation for every key matching pattern.
+type eachKeyExpectation struct {
+	pattern *regexp.Regexp
+	exp     Node
+}
+
+type eachKeyExpectations []eachKeyExpectation
+
+// find returns the expectations for key.
+func (e eachKeyExpectations) find(key string) []Node {
+	var nodes []Node
+	for _, ek := range e {
+		if ek.pattern.MatchString(key) {
+			nodes = append(nodes, ek.exp)
+		}
+	}
+	return nodes
+}
+
+// createEachKeyExpectations returns the expectations for fields like "_gst_each_key(^user_\\d+$)".  The fields are
+// removed from obj.
+func createEachKeyExpectations(obj map[string]Node) (eachKeyExpectations, error) {
+	var result eachKeyExpectations
+	for _, k := range sortedKeys(obj) {
+		m := patternEachKey.FindStringSubmatch(k)
+		if m == nil {
+			continue
+		}
+		pattern, err := regexp.Compile(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex in '%s': %s", k, err.Error())
+		}
+		result = append(result, eachKeyExpectation{
+			pattern: pattern,
+			exp:     obj[k],
+		})
+		delete(obj, k)
+	}
+	return result, nil
+}
+
 func isBeEmpty(node Node) bool {
-	return node.Type == String && patternEmpty.Match(node.Value)
+	return node.Type == String && !isLiteral(node) && patternEmpty.Match(node.Value)
+}
+
+func isLiteral(node Node) bool {
+	return node.Type == String && patternLiteral.Match(node.Value)
+}
+
+func literalText(input string) string {
+	return patternLiteral.FindStringSubmatch(input)[1]
+}
+
+// sortedKeys returns the keys of m in sorted order, so that failures are reported deterministically.
+func sortedKeys(m map[string]Node) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func mapSubexpNames(m, n []string) map[string]string {
@@ -431,25 +742,113 @@ func createActualIDMapper(nodes []Node, key string, parser Parser) (map[string]N
 // =====================
 
 // Replace returns data with all variables replaced with values in vars.  If any variable is not defined in vars, an error is returned.
+//
+// Generator variables (e.g. ${{@uuid}}) are evaluated by a new Generator.
 func Replace(data []byte, vars map[string]string) ([]byte, error) {
-	ms := patternVariable.FindAllSubmatch(data, -1)
-	ns := map[string]bool{}
-	for _, m := range ms {
-		ns[string(m[1])] = true
-	}
-	var names []string
-	for k, _ := range ns {
-		names = append(names, k)
+	now := time.Now()
+	return ReplaceWithGenerator(data, vars, NewGenerator(now.UnixNano(), now))
+}
+
+// ReplaceWithGenerator is like Replace, but generator variables are evaluated by gen.
+//
+// Text inside {{Literal(...)}} is left untouched, and an escaped variable $${{MY_VAR}} is replaced with ${{MY_VAR}}.
+func ReplaceWithGenerator(data []byte, vars map[string]string, gen *Generator) ([]byte, error) {
+	result, _, err := replace(data, vars, gen)
+	return result, err
+}
+
+// Variable is a variable in a golden document, e.g. ${{MY_VAR}} or ${{@uuid}}.
+type Variable struct {
+	// Name is the name of the variable, with "@" for generator variables
+	Name string
+	// Offset is the offset of the variable in the document
+	Offset int
+}
+
+// Variables returns the variables in data in order.  Escaped variables and variables inside {{Literal(...)}} are
+// excluded.
+func Variables(data []byte) []Variable {
+	literals := patternLiteralSpan.FindAllIndex(data, -1)
+	var result []Variable
+	for _, m := range patternSubstitution.FindAllSubmatchIndex(data, -1) {
+		if m[2] >= 0 {
+			continue
+		}
+		inLiteral := false
+		for _, loc := range literals {
+			if m[0] >= loc[0] && m[0] < loc[1] {
+				inLiteral = true
+				break
+			}
+		}
+		if !inLiteral {
+			result = append(result, Variable{
+				Name:   string(data[m[4]:m[5]]),
+				Offset: m[0],
+			})
+		}
 	}
+	return result
+}
+
+// substitution records that data[origStart:origEnd] was replaced with result[start:end].
+type substitution struct {
+	start, end         int
+	origStart, origEnd int
+}
 
-	result := string(data)
-	for _, name := range names {
-		value, ok := vars[name]
-		if !ok {
-			return nil, fmt.Errorf("variable '%s' undefined in substitution", name)
+func replace(data []byte, vars map[string]string, gen *Generator) ([]byte, []substitution, error) {
+	var result []byte
+	var subs []substitution
+	var err error
+	start := 0
+	for _, loc := range patternLiteralSpan.FindAllIndex(data, -1) {
+		result, subs, err = replaceSegment(result, subs, data, start, loc[0], vars, gen)
+		if err != nil {
+			return nil, nil, err
 		}
-		result = strings.Replace(result, fmt.Sprintf("${{%s}}", name), value, -1)
+		result = append(result, data[loc[0]:loc[1]]...)
+		start = loc[1]
 	}
+	return replaceSegment(result, subs, data, start, len(data), vars, gen)
+}
 
-	return []byte(result), nil
+// replaceSegment appends data[from:to] to result with variables replaced.
+func replaceSegment(result []byte, subs []substitution, data []byte, from, to int, vars map[string]string, gen *Generator) ([]byte, []substitution, error) {
+	segment := data[from:to]
+	start := 0
+	for _, m := range patternSubstitution.FindAllSubmatchIndex(segment, -1) {
+		result = append(result, segment[start:m[0]]...)
+		start = m[1]
+		var value []byte
+		if m[2] >= 0 {
+			// Escaped, drop the leading "$"
+			value = segment[m[0]+1 : m[1]]
+		} else if name := string(segment[m[4]:m[5]]); strings.HasPrefix(name, "@") {
+			// vars can replace generator variables too, e.g. with placeholders for invalid ones
+			v, ok := vars[name]
+			if !ok {
+				var err error
+				v, err = gen.Value(name[1:])
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			value = []byte(v)
+		} else {
+			v, ok := vars[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("variable '%s' undefined in substitution", name)
+			}
+			value = []byte(v)
+		}
+		subs = append(subs, substitution{
+			start:     len(result),
+			end:       len(result) + len(value),
+			origStart: from + m[0],
+			origEnd:   from + m[1],
+		})
+		result = append(result, value...)
+	}
+	return append(result, segment[start:]...), subs, nil
 }
@@ -1,7 +1,9 @@
 package matcher
 
 import (
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestWalk_String(t *testing.T) {
@@ -968,3 +970,273 @@ func TestReplace_Failure_NoValue(t *testing.T) {
 		t.Fatalf("err should not be nil but was %+v", err)
 	}
 }
+
+func TestReplaceWithGenerator(t *testing.T) {
+	data := []byte(`${{@uuid}} ${{FOO}} ${{@uuid}} {{BeTimestamp(${{@now}}, 5000)}}`)
+	gen := NewGenerator(1, time.Date(2018, 10, 5, 12, 13, 14, 0, time.UTC))
+	id, _ := gen.Value("uuid")
+
+	res, err := ReplaceWithGenerator(data, map[string]string{"FOO": "Ethan"}, gen)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if string(res) != id+` Ethan `+id+` {{BeTimestamp(2018-10-05T12:13:14.000Z, 5000)}}` {
+		t.Fatalf("ReplaceWithGenerator() result incorrect, %s", string(res))
+	}
+}
+
+func TestReplace_Failure_UnknownGenerator(t *testing.T) {
+	_, err := Replace([]byte(`${{@foo}}`), nil)
+	if err == nil {
+		t.Fatalf("err should not be nil but was %+v", err)
+	}
+}
+
+func TestReplace_Escape(t *testing.T) {
+	data := []byte(`{"a": "$${{FOO}}", "b": "${{FOO}}", "c": "{{Literal(${{FOO}} {{BeEmpty()}})}}", "d": "${{FOO}}"}`)
+
+	res, err := Replace(data, map[string]string{"FOO": "Ethan"})
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if string(res) != `{"a": "${{FOO}}", "b": "Ethan", "c": "{{Literal(${{FOO}} {{BeEmpty()}})}}", "d": "Ethan"}` {
+		t.Fatalf("Replace() result incorrect, %s", string(res))
+	}
+}
+
+func TestReplace_EscapeYAML(t *testing.T) {
+	data := []byte("a: '{{Literal(x ${{X}})}}'\nb: '${{X}}'\nc: {{Literal(y)}}\nd: ${{X}}\ne: '{{Literal(y)}}'\n")
+
+	res, err := Replace(data, map[string]string{"X": "1"})
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if string(res) != "a: '{{Literal(x ${{X}})}}'\nb: '1'\nc: {{Literal(y)}}\nd: 1\ne: '{{Literal(y)}}'\n" {
+		t.Fatalf("Replace() result incorrect, %s", string(res))
+	}
+	if _, err := Replace(data, nil); err == nil || err.Error() != "variable 'X' undefined in substitution" {
+		t.Fatalf("err incorrect, %+v", err)
+	}
+	if vars := Variables(data); len(vars) != 2 || vars[0].Name != "X" || vars[1].Name != "X" {
+		t.Fatalf("Variables() result incorrect, %+v", vars)
+	}
+}
+
+func TestWalk_Literal(t *testing.T) {
+	exp := Node{
+		Type: Object,
+		Value: []byte(`
+		{
+			"field0": "{{Literal({{BeEmpty()}})}}",
+			"field1": "{{Literal(Hello {{name}})}}"
+		}
+	`),
+	}
+	act := Node{
+		Type: Object,
+		Value: []byte(`
+		{
+			"field0": "{{BeEmpty()}}",
+			"field1": "Hello {{name}}"
+		}
+	`),
+	}
+	matcher, matched, err := Walk("", exp, act, JSONParserInstance)
+	if matcher != SuccessMatcherInstance {
+		t.Fatalf("matcher should be SuccessMatcherInstance but was %+v, matched = %t, err = %+v", matcher, matched, err)
+	}
+	if !matched {
+		t.Fatalf("matched should be true")
+	}
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+}
+
+func TestWalk_Literal_Failure(t *testing.T) {
+	exp := Node{
+		Type: Object,
+		Value: []byte(`
+		{
+			"field0": "{{Literal({{BeEmpty()}})}}"
+		}
+	`),
+	}
+	for _, actual := range []string{`{}`, `{"field0": ""}`, `{"field0": []}`} {
+		act := Node{
+			Type:  Object,
+			Value: []byte(actual),
+		}
+		matcher, matched, _ := Walk("", exp, act, JSONParserInstance)
+		if matcher == SuccessMatcherInstance {
+			t.Fatalf("matcher should not be SuccessMatcherInstance for %s", actual)
+		}
+		if matched {
+			t.Fatalf("matched should be false for %s", actual)
+		}
+	}
+}
+
+func TestWalk_Values(t *testing.T) {
+	exp := Node{
+		Type: Object,
+		Value: []byte(`{
+			"users": {
+				"_gst_values": {"name": "{{Not(BeEmpty())}}", "age": "{{BeNumerically(>, 0)}}"},
+				"admin": {"name": "root"}
+			}
+		}`),
+	}
+	act := Node{
+		Type:  Object,
+		Value: []byte(`{"users": {"u1": {"name": "Ethan", "age": 36}, "u2": {"name": "", "age": 0}, "admin": {"name": "root"}}}`),
+	}
+	w := NewWalker(JSONParserInstance)
+	w.ContinueOnFailure = true
+	w.StrictObjects = true
+	_, matched, _ := w.Walk("", exp, act)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	var paths []string
+	for _, f := range w.Failures() {
+		paths = append(paths, f.Path)
+	}
+	if strings.Join(paths, ",") != ".users.u2.age,.users.u2.name" {
+		t.Fatalf("failures incorrect, %+v", paths)
+	}
+
+	act.Value = []byte(`{"users": {"u1": {"name": "Ethan", "age": 36}, "admin": {"name": "root"}}}`)
+	matcher, matched, err := Walk("", exp, act, JSONParserInstance)
+	if matcher != SuccessMatcherInstance {
+		t.Fatalf("matcher should be SuccessMatcherInstance but was %+v, matched = %t, err = %+v", matcher, matched, err)
+	}
+}
+
+func TestWalk_EachKey(t *testing.T) {
+	exp := Node{
+		Type: Object,
+		Value: []byte(`{
+			"_gst_each_key(^user_\\d+$)": {"name": "{{Not(BeEmpty())}}"},
+			"_gst_values": "{{BeNumerically(>, 0)}}",
+			"version": 2
+		}`),
+	}
+	act := Node{
+		Type:  Object,
+		Value: []byte(`{"user_1": {"name": "Ethan"}, "user_2": {"name": ""}, "user_3": {}, "count": 0, "version": 2}`),
+	}
+	w := NewWalker(JSONParserInstance)
+	w.ContinueOnFailure = true
+	_, matched, _ := w.Walk("", exp, act)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	var failures []string
+	for _, f := range w.Failures() {
+		failures = append(failures, f.Path+":"+string(f.Kind))
+	}
+	if strings.Join(failures, ",") != ".count:mismatch,.user_2.name:mismatch,.user_3.name:missing" {
+		t.Fatalf("failures incorrect, %+v", failures)
+	}
+
+	exp.Value = []byte(`{"_gst_each_key(^user_\\d+$)": {"name": "{{Not(BeEmpty())}}"}}`)
+	act.Value = []byte(`{"user_1": {"name": "Ethan"}, "count": 0}`)
+	w = NewWalker(JSONParserInstance)
+	w.StrictObjects = true
+	matcher, matched, _ := w.Walk("", exp, act)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if fm := matcher.(*FailureMatcher); fm.Path != ".count" || fm.Kind != FailureUnexpected {
+		t.Fatalf("failure incorrect, %+v", fm)
+	}
+
+	exp.Value = []byte(`{"_gst_each_key(^user_[)": {}}`)
+	_, matched, err := Walk("", exp, act, JSONParserInstance)
+	if matched || err == nil || !strings.Contains(err.Error(), "invalid regex in '_gst_each_key(^user_[)'") {
+		t.Fatalf("err incorrect, %+v", err)
+	}
+}
+
+func TestWalk_Each(t *testing.T) {
+	exp := Node{
+		Type: Object,
+		Value: []byte(`{
+			"connections": [
+				{"_gst_each": {"id": "{{Not(BeEmpty())}}", "relationship": "{{BeOneOf(ex, unclear)}}"}},
+				{"_gst_id": "id=0002", "name": "Julia Meade"}
+			],
+			"scores": [{"_gst_each": "{{BeNumerically(>=, 0)}}"}],
+			"tags": "{{Each({{Not(BeEmpty())}})}}"
+		}`),
+	}
+	act := Node{
+		Type: Object,
+		Value: []byte(`{
+			"connections": [
+				{"id": "0002", "name": "Julia Meade", "relationship": "ex"},
+				{"id": "", "name": "Benji Dunn", "relationship": "team mate"},
+				{"id": "0003", "name": "Ilsa Faust", "relationship": "unclear"}
+			],
+			"scores": [1, -1, 2, -3],
+			"tags": ["a", ""]
+		}`),
+	}
+	w := NewWalker(JSONParserInstance)
+	w.ContinueOnFailure = true
+	_, matched, _ := w.Walk("", exp, act)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	var paths []string
+	for _, f := range w.Failures() {
+		paths = append(paths, f.Path)
+	}
+	expected := ".connections[1].id,.connections[1].relationship,.scores[1],.scores[3],.tags[1]"
+	if strings.Join(paths, ",") != expected {
+		t.Fatalf("failures should be %s but were %s", expected, strings.Join(paths, ","))
+	}
+	if !strings.Contains(w.Failures()[1].Reason, "to be one of") {
+		t.Fatalf("reason incorrect, %s", w.Failures()[1].Reason)
+	}
+
+	act.Value = []byte(`{
+		"connections": [{"id": "0002", "name": "Julia Meade", "relationship": "ex"}],
+		"scores": [],
+		"tags": ["a"]
+	}`)
+	matcher, matched, err := Walk("", exp, act, JSONParserInstance)
+	if matcher != SuccessMatcherInstance {
+		t.Fatalf("matcher should be SuccessMatcherInstance but was %+v, matched = %t, err = %+v", matcher, matched, err)
+	}
+}
+
+func TestWalk_EachObject(t *testing.T) {
+	exp := Node{
+		Type:  Object,
+		Value: []byte(`{"users": "{{Each({\"id\": \"{{Not(BeEmpty())}}\"})}}", "matrix": "{{Each([1, 2])}}"}`),
+	}
+	act := Node{
+		Type:  Object,
+		Value: []byte(`{"users": [{"id": "0001"}, {"id": ""}, {"name": "Ethan"}], "matrix": [[1, 2], [1, 3]]}`),
+	}
+	w := NewWalker(JSONParserInstance)
+	w.ContinueOnFailure = true
+	_, matched, _ := w.Walk("", exp, act)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	var failures []string
+	for _, f := range w.Failures() {
+		failures = append(failures, f.Path+":"+string(f.Kind))
+	}
+	if strings.Join(failures, ",") != ".matrix[1]:mismatch,.users[1].id:mismatch,.users[2].id:missing" {
+		t.Fatalf("failures incorrect, %+v", failures)
+	}
+
+	act.Value = []byte(`{"users": [{"id": "0001", "name": "Ethan"}], "matrix": []}`)
+	if _, matched, err := Walk("", exp, act, JSONParserInstance); !matched || err != nil {
+		t.Fatalf("matched should be true but was %t, err = %+v", matched, err)
+	}
+}
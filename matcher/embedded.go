@@ -0,0 +1,181 @@
+package matcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/onsi/gomega/types"
+)
+
+// EmbeddedJSON is the format of a JSON document embedded in a string.
+const EmbeddedJSON = "json"
+
+var (
+	// Decoders decode actual strings before they are matched, by name.  They can be used in the KeyEmbedded marker,
+	// e.g. "base64|gzip|json", or as functions, e.g. {{Base64(Gzip(hello))}}.
+	Decoders = map[string]func([]byte) ([]byte, error){
+		"base64": decodeBase64,
+		"gzip":   decodeGzip,
+		"hex":    decodeHex,
+	}
+
+	// Usage: {{Base64(<nested expected>)}}, which decodes the actual string and matches it with the nested expected
+	// value
+	patternDecoder = regexp.MustCompile(`(?s)^(?:{{)?(?P<name>Base64|Gzip|Hex)\((?P<nested>.*)\)(?:}})?$`)
+)
+
+// Unescaper is implemented by parsers that return String values escaped, e.g. JSON.
+type Unescaper interface {
+	// Unescape returns the unescaped value of a String node.
+	Unescape(value []byte) ([]byte, error)
+}
+
+// isEmbedded returns true if exp is an object with the KeyEmbedded marker.
+func isEmbedded(exp Node, parser Parser) bool {
+	if parser.ValidateObject(exp.Value) != nil {
+		return false
+	}
+	_, ok := parser.GetFields(exp.Value)[KeyEmbedded]
+	return ok
+}
+
+// isDecoderCall returns true if exp is a string like {{Base64(...)}}.
+func isDecoderCall(exp Node) bool {
+	return exp.Type == String && bytes.HasPrefix(exp.Value, []byte("{{")) && patternDecoder.Match(exp.Value)
+}
+
+// walkEmbedded matches the document embedded in the actual string act with the expected object exp, e.g.
+//
+//	{
+//	  "_gst_embedded": "json",
+//	  "orderId": "1234"
+//	}
+//
+// The marker can be a pipeline of decoders ending with the format, e.g. "base64|gzip|json".  The document is walked
+// with the same parser.  Paths in the document have the pipeline as suffix, e.g. ".payload|json.orderId".
+func (w *Walker) walkEmbedded(path string, exp, act Node) (types.GomegaMatcher, bool, error) {
+	marker := w.Parser.GetFields(exp.Value)[KeyEmbedded]
+	decoders, err := embeddedDecoders(marker)
+	if err != nil {
+		return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Raw())), FailureMismatch, err)
+	}
+	data, err := unescape(act, w.Parser)
+	if err != nil {
+		return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Raw())), FailureMismatch, err)
+	}
+	for _, stage := range decoders {
+		path += "|" + stage
+		if data, err = Decoders[stage](data); err != nil {
+			return w.fail(NewFailureMatcher(path, string(marker.Value), string(act.Raw())), FailureMismatch, err)
+		}
+	}
+	expNode := Node{
+		Type:  Object,
+		Value: w.Parser.Delete(exp.Value, KeyEmbedded),
+	}
+	actNode := Node{
+		Type:  Object,
+		Value: data,
+	}
+	return w.Walk(path+"|"+EmbeddedJSON, expNode, actNode)
+}
+
+// embeddedDecoders returns the decoders in the KeyEmbedded marker, e.g. ["base64", "gzip"] for "base64|gzip|json".
+func embeddedDecoders(marker Node) ([]string, error) {
+	stages := strings.Split(string(marker.Value), "|")
+	if marker.Type != String || stages[len(stages)-1] != EmbeddedJSON {
+		return nil, fmt.Errorf("'%s' field must be '%s', optionally preceded by decoders (e.g. 'base64|%s'), was %s", KeyEmbedded, EmbeddedJSON, EmbeddedJSON, string(marker.Raw()))
+	}
+	for _, stage := range stages[:len(stages)-1] {
+		if _, ok := Decoders[stage]; !ok {
+			return nil, fmt.Errorf("unknown decoder '%s' in '%s' field", stage, KeyEmbedded)
+		}
+	}
+	return stages[:len(stages)-1], nil
+}
+
+// walkDecoded decodes the actual string act and matches it with the expected value nested in a decoder call, e.g.
+// {{Base64(hello)}}, {{Base64(Gzip({{BeEmpty()}}))}} or {{Base64({"orderId": "1234"})}}.  Paths have the decoders as
+// suffix, e.g. ".blob|base64|gzip".
+func (w *Walker) walkDecoded(path string, exp, act Node) (types.GomegaMatcher, bool, error) {
+	if act.Type != String {
+		return w.fail(NewFailureMatcher(path, exp.Type.String(), act.Type.String()), FailureType, fmt.Errorf("decoders can only be used with String but path has type %s", act.Type.String()))
+	}
+	expr, err := unescape(exp, w.Parser)
+	if err != nil {
+		return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
+	}
+	data, err := unescape(act, w.Parser)
+	if err != nil {
+		return w.fail(NewFailureMatcher(path, string(exp.Value), string(act.Value)), FailureMismatch, err)
+	}
+	for {
+		m := patternDecoder.FindSubmatch(expr)
+		if m == nil {
+			break
+		}
+		name := strings.ToLower(string(m[1]))
+		path += "|" + name
+		if data, err = Decoders[name](data); err != nil {
+			return w.fail(NewFailureMatcher(path, string(expr), string(act.Value)), FailureMismatch, err)
+		}
+		expr = m[2]
+	}
+
+	trimmed := bytes.TrimSpace(expr)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return w.Walk(path+"|"+EmbeddedJSON, Node{Type: Array, Value: trimmed}, Node{Type: Array, Value: bytes.TrimSpace(data)})
+	case bytes.HasPrefix(trimmed, []byte("{")) && !bytes.HasPrefix(trimmed, []byte("{{")):
+		return w.Walk(path+"|"+EmbeddedJSON, Node{Type: Object, Value: trimmed}, Node{Type: Object, Value: data})
+	}
+	return w.Walk(path, Node{Type: String, Value: expr}, Node{Type: String, Value: data})
+}
+
+// unescape returns the unescaped value of a String node.
+func unescape(node Node, parser Parser) ([]byte, error) {
+	if u, ok := parser.(Unescaper); ok {
+		return u.Unescape(node.Value)
+	}
+	return node.Value, nil
+}
+
+// decodeBase64 decodes standard or URL-safe base64, with or without padding.
+func decodeBase64(data []byte) ([]byte, error) {
+	s := strings.TrimSpace(string(data))
+	var err error
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var decoded []byte
+		if decoded, err = encoding.DecodeString(s); err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot decode base64: %s", err.Error())
+}
+
+func decodeGzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode gzip: %s", err.Error())
+	}
+	defer r.Close()
+	decoded, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode gzip: %s", err.Error())
+	}
+	return decoded, nil
+}
+
+func decodeHex(data []byte) ([]byte, error) {
+	decoded, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode hex: %s", err.Error())
+	}
+	return decoded, nil
+}
@@ -0,0 +1,151 @@
+package matcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWalk_Embedded(t *testing.T) {
+	exp := Node{
+		Type: Object,
+		Value: []byte(`{
+			"payload": {
+				"_gst_embedded": "json",
+				"orderId": "1234",
+				"items": ["a", "b"],
+				"note": "{{BeEmpty()}}"
+			}
+		}`),
+	}
+	act := Node{
+		Type:  Object,
+		Value: []byte(`{"payload": "{\"orderId\": \"1234\", \"items\": [\"b\", \"a\"]}"}`),
+	}
+	matcher, matched, err := Walk("", exp, act, JSONParserInstance)
+	if matcher != SuccessMatcherInstance {
+		t.Fatalf("matcher should be SuccessMatcherInstance but was %+v, matched = %t, err = %+v", matcher, matched, err)
+	}
+
+	// Matching again must give the same result
+	matcher, matched, err = Walk("", exp, act, JSONParserInstance)
+	if matcher != SuccessMatcherInstance {
+		t.Fatalf("matcher should be SuccessMatcherInstance but was %+v, matched = %t, err = %+v", matcher, matched, err)
+	}
+
+	act.Value = []byte(`{"payload": "{\"orderId\": \"5678\", \"items\": [\"b\", \"a\"]}"}`)
+	matcher, matched, _ = Walk("", exp, act, JSONParserInstance)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	fm := matcher.(*FailureMatcher)
+	if fm.Path != ".payload|json.orderId" || fm.Expected != "1234" || fm.Actual != "5678" {
+		t.Fatalf("failure incorrect, %+v", fm)
+	}
+}
+
+func TestWalk_Embedded_Errors(t *testing.T) {
+	cases := []struct {
+		exp  string
+		act  string
+		path string
+		err  string
+	}{
+		{`{"payload": {"_gst_embedded": "xml"}}`, `{"payload": "{}"}`, ".payload", "'_gst_embedded' field must be 'json'"},
+		{`{"payload": {"_gst_embedded": "rot13|json"}}`, `{"payload": "{}"}`, ".payload", "unknown decoder 'rot13'"},
+		{`{"payload": {"_gst_embedded": "base64|json"}}`, `{"payload": "!!!"}`, ".payload|base64", "cannot decode base64"},
+		{`{"payload": {"_gst_embedded": "json"}}`, `{"payload": "not json"}`, ".payload|json", "invalid character"},
+	}
+	for _, c := range cases {
+		exp := Node{
+			Type:  Object,
+			Value: []byte(c.exp),
+		}
+		act := Node{
+			Type:  Object,
+			Value: []byte(c.act),
+		}
+		matcher, matched, err := Walk("", exp, act, JSONParserInstance)
+		if matched || err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("%s: err should contain %q but was %+v", c.exp, c.err, err)
+		}
+		if fm := matcher.(*FailureMatcher); fm.Path != c.path {
+			t.Fatalf("%s: path should be %s but was %s", c.exp, c.path, fm.Path)
+		}
+	}
+}
+
+func gzipBase64(t *testing.T, s string) string {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestWalk_Embedded_Pipeline(t *testing.T) {
+	exp := Node{
+		Type:  Object,
+		Value: []byte(`{"blob": {"_gst_embedded": "base64|gzip|json", "orderId": "1234"}}`),
+	}
+	act := Node{
+		Type:  Object,
+		Value: []byte(`{"blob": "` + gzipBase64(t, `{"orderId": "5678"}`) + `"}`),
+	}
+	matcher, matched, _ := Walk("", exp, act, JSONParserInstance)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if fm := matcher.(*FailureMatcher); fm.Path != ".blob|base64|gzip|json.orderId" {
+		t.Fatalf("path incorrect, %s", fm.Path)
+	}
+}
+
+func TestWalk_Decoders(t *testing.T) {
+	hello := base64.StdEncoding.EncodeToString([]byte("hello"))
+	order := base64.StdEncoding.EncodeToString([]byte(`{"orderId": "1234", "items": [1, 2]}`))
+	cases := []struct {
+		exp     string
+		act     string
+		matched bool
+		path    string
+	}{
+		{"{{Base64(hello)}}", hello, true, ""},
+		{"{{Base64(world)}}", hello, false, ".v|base64"},
+		{"{{Base64({{Not(BeEmpty())}})}}", hello, true, ""},
+		{"{{Hex(Base64(hello))}}", hex.EncodeToString([]byte(hello)), true, ""},
+		{"{{Base64(Gzip(hello))}}", gzipBase64(t, "hello"), true, ""},
+		{"{{Base64(Gzip(hello))}}", hello, false, ".v|base64|gzip"},
+		{`{{Base64({\"orderId\": \"1234\"})}}`, order, true, ""},
+		{`{{Base64({\"orderId\": \"5678\"})}}`, order, false, ".v|base64|json.orderId"},
+		{`{{Base64({\"items\": [2, 1]})}}`, order, true, ""},
+		{"{{Hex(hello)}}", "zz", false, ".v|hex"},
+	}
+	for _, c := range cases {
+		exp := Node{
+			Type:  Object,
+			Value: []byte(`{"v": "` + c.exp + `"}`),
+		}
+		act := Node{
+			Type:  Object,
+			Value: []byte(`{"v": ` + strconv.Quote(c.act) + `}`),
+		}
+		matcher, matched, err := Walk("", exp, act, JSONParserInstance)
+		if matched != c.matched {
+			t.Fatalf("%s: matched should be %t but was %t, err = %+v", c.exp, c.matched, matched, err)
+		}
+		if !matched {
+			if fm := matcher.(*FailureMatcher); fm.Path != c.path {
+				t.Fatalf("%s: path should be %s but was %s", c.exp, c.path, fm.Path)
+			}
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package matcher
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/onsi/gomega/types"
+)
+
+func init() {
+	RegisterFunction("BeUUID", newBeUUIDMatcher)
+	RegisterFunction("BeEmail", newBeEmailMatcher)
+	RegisterFunction("BeURL", newBeURLMatcher)
+	RegisterFunction("BeIP", newBeIPMatcher)
+	RegisterFunction("BeCIDR", newBeCIDRMatcher)
+	RegisterFunction("BeBase64", newBeBase64Matcher)
+	RegisterFunction("BeSemver", newBeSemverMatcher)
+	RegisterFunction("BeDuration", newBeDurationMatcher)
+}
+
+// FormatMatcher matches strings of a format, e.g. UUID.  The failure message explains which rule of the format was
+// broken.
+type FormatMatcher struct {
+	// Format is the name of the format, e.g. "a UUID"
+	Format string
+	// Validate returns an error if the string does not have the format
+	Validate func(string) error
+
+	err error
+}
+
+// NewFormatMatcher returns a new *FormatMatcher.
+func NewFormatMatcher(format string, validate func(string) error) *FormatMatcher {
+	return &FormatMatcher{
+		Format:   format,
+		Validate: validate,
+	}
+}
+
+// Match matches a `string`.
+func (matcher *FormatMatcher) Match(actual interface{}) (bool, error) {
+	if actual == nil {
+		matcher.err = fmt.Errorf("value is missing")
+		return false, nil
+	}
+	str, ok := actual.(string)
+	if !ok {
+		return false, fmt.Errorf("FormatMatcher expects a string")
+	}
+	matcher.err = matcher.Validate(str)
+	return matcher.err == nil, nil
+}
+
+// FailureMessage returns failure message.
+func (matcher *FormatMatcher) FailureMessage(actual interface{}) string {
+	if matcher.err == nil {
+		return fmt.Sprintf("Expected %q to be %s", actual, matcher.Format)
+	}
+	return fmt.Sprintf("Expected %q to be %s: %s", actual, matcher.Format, matcher.err.Error())
+}
+
+// NegatedFailureMessage returns negated failure message.
+func (matcher *FormatMatcher) NegatedFailureMessage(actual interface{}) string {
+	return fmt.Sprintf("Expected %q not to be %s", actual, matcher.Format)
+}
+
+// Usage: {{BeUUID()}} or {{BeUUID(v4)}}, which also checks the version
+func newBeUUIDMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 0, 1); err != nil {
+		return nil, err
+	}
+	var version byte
+	format := "a UUID"
+	if len(args) == 1 {
+		if len(args[0]) != 2 || args[0][0] != 'v' || args[0][1] < '1' || args[0][1] > '5' {
+			return nil, fmt.Errorf("version must be one of v1 to v5 but was %s", args[0])
+		}
+		version = args[0][1]
+		format = fmt.Sprintf("a UUID %s", args[0])
+	}
+	return NewFormatMatcher(format, func(s string) error {
+		if len(s) != 36 {
+			return fmt.Errorf("must have 36 characters but has %d", len(s))
+		}
+		for i := 0; i < len(s); i++ {
+			switch i {
+			case 8, 13, 18, 23:
+				if s[i] != '-' {
+					return fmt.Errorf("character %d must be '-' but is %q", i+1, s[i])
+				}
+			default:
+				if !isHexDigit(s[i]) {
+					return fmt.Errorf("character %d must be a hex digit but is %q", i+1, s[i])
+				}
+			}
+		}
+		if version != 0 {
+			if s[14] != version {
+				return fmt.Errorf("version must be %c but is %c", version, s[14])
+			}
+			if !strings.ContainsRune("89abAB", rune(s[19])) {
+				return fmt.Errorf("variant must be RFC 4122 (character 20 must be one of 8, 9, a, b) but is %q", s[19])
+			}
+		}
+		return nil
+	}), nil
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// Usage: {{BeEmail()}}, which means a plain address like "user@example.com" (without display name)
+func newBeEmailMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 0, 0); err != nil {
+		return nil, err
+	}
+	return NewFormatMatcher("an email address", func(s string) error {
+		at := strings.LastIndexByte(s, '@')
+		if at < 0 {
+			return fmt.Errorf("must contain '@'")
+		}
+		if at == 0 {
+			return fmt.Errorf("local part (before '@') must not be empty")
+		}
+		domain := s[at+1:]
+		if domain == "" {
+			return fmt.Errorf("domain (after '@') must not be empty")
+		}
+		if !strings.Contains(domain, ".") {
+			return fmt.Errorf("domain %s must contain '.'", domain)
+		}
+		addr, err := mail.ParseAddress(s)
+		if err != nil {
+			return err
+		}
+		if addr.Address != s || addr.Name != "" {
+			return fmt.Errorf("must be a plain address without display name or comments")
+		}
+		return nil
+	}), nil
+}
+
+// Usage: {{BeURL()}} or {{BeURL(http, https)}}, which also checks the scheme
+func newBeURLMatcher(args []string) (types.GomegaMatcher, error) {
+	format := "a URL"
+	if len(args) > 0 {
+		format = fmt.Sprintf("a URL with scheme %s", strings.Join(args, " or "))
+	}
+	return NewFormatMatcher(format, func(s string) error {
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "" {
+			return fmt.Errorf("scheme is missing")
+		}
+		if len(args) > 0 && !containsString(args, u.Scheme) {
+			return fmt.Errorf("scheme must be one of %s but is %s", strings.Join(args, ", "), u.Scheme)
+		}
+		if u.Host == "" && u.Opaque == "" {
+			return fmt.Errorf("host is missing")
+		}
+		return nil
+	}), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Usage: {{BeIP()}}, {{BeIP(v4)}} or {{BeIP(v6)}}
+func newBeIPMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 0, 1); err != nil {
+		return nil, err
+	}
+	version := ""
+	if len(args) == 1 {
+		version = args[0]
+		if version != "v4" && version != "v6" {
+			return nil, fmt.Errorf("version must be v4 or v6 but was %s", version)
+		}
+	}
+	format := "an IP address"
+	if version != "" {
+		format = fmt.Sprintf("an IP%s address", version)
+	}
+	return NewFormatMatcher(format, func(s string) error {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("must be a dotted decimal (IPv4) or colon separated hex (IPv6) address")
+		}
+		isV4 := ip.To4() != nil && !strings.Contains(s, ":")
+		switch {
+		case version == "v4" && !isV4:
+			return fmt.Errorf("must be an IPv4 address but is IPv6")
+		case version == "v6" && isV4:
+			return fmt.Errorf("must be an IPv6 address but is IPv4")
+		}
+		return nil
+	}), nil
+}
+
+// Usage: {{BeCIDR()}}, e.g. "10.0.0.0/8"
+func newBeCIDRMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 0, 0); err != nil {
+		return nil, err
+	}
+	return NewFormatMatcher("a CIDR", func(s string) error {
+		slash := strings.IndexByte(s, '/')
+		if slash < 0 {
+			return fmt.Errorf("must contain '/' followed by the prefix length")
+		}
+		if net.ParseIP(s[:slash]) == nil {
+			return fmt.Errorf("%s is not an IP address", s[:slash])
+		}
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			return fmt.Errorf("prefix length %s is invalid", s[slash+1:])
+		}
+		return nil
+	}), nil
+}
+
+// Usage: {{BeBase64()}} for standard encoding, {{BeBase64(url)}} for URL encoding.  Padding is required.
+func newBeBase64Matcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 0, 1); err != nil {
+		return nil, err
+	}
+	encoding := base64.StdEncoding
+	format := "base64"
+	if len(args) == 1 {
+		if args[0] != "url" {
+			return nil, fmt.Errorf("encoding must be url but was %s", args[0])
+		}
+		encoding = base64.URLEncoding
+		format = "URL-safe base64"
+	}
+	return NewFormatMatcher(format, func(s string) error {
+		if len(s)%4 != 0 {
+			return fmt.Errorf("length must be a multiple of 4 (padded with '=') but is %d", len(s))
+		}
+		if _, err := encoding.DecodeString(s); err != nil {
+			if e, ok := err.(base64.CorruptInputError); ok {
+				// The offset is len(s) if the input ends early, e.g. "QQ=\n" where newlines are ignored
+				switch offset := int(e); {
+				case offset >= len(s):
+					return fmt.Errorf("is truncated, padding is incomplete")
+				case s[offset] == '=':
+					return fmt.Errorf("padding '=' at character %d is misplaced", offset+1)
+				default:
+					return fmt.Errorf("character %d (%q) is not in the alphabet", offset+1, s[offset])
+				}
+			}
+			return err
+		}
+		return nil
+	}), nil
+}
+
+// Usage: {{BeSemver()}}, e.g. "1.2.3-beta.1+build.5" (see https://semver.org)
+func newBeSemverMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 0, 0); err != nil {
+		return nil, err
+	}
+	return NewFormatMatcher("a semantic version", validateSemver), nil
+}
+
+func validateSemver(s string) error {
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		if err := validateSemverIdentifiers("build metadata", s[i+1:], false); err != nil {
+			return err
+		}
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		if err := validateSemverIdentifiers("pre-release", s[i+1:], true); err != nil {
+			return err
+		}
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("must have the form MAJOR.MINOR.PATCH but %s has %d parts", s, len(parts))
+	}
+	for i, p := range parts {
+		name := []string{"major", "minor", "patch"}[i]
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return fmt.Errorf("%s version %q must be a number", name, p)
+		}
+		if len(p) > 1 && p[0] == '0' {
+			return fmt.Errorf("%s version %s must not have leading zeros", name, p)
+		}
+	}
+	return nil
+}
+
+func validateSemverIdentifiers(name, s string, noLeadingZeros bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("%s must not have empty identifiers", name)
+		}
+		for _, c := range id {
+			if !(c == '-' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+				return fmt.Errorf("%s identifier %s must only contain [0-9A-Za-z-]", name, id)
+			}
+		}
+		if noLeadingZeros && len(id) > 1 && id[0] == '0' && strings.Trim(id, "0123456789") == "" {
+			return fmt.Errorf("numeric %s identifier %s must not have leading zeros", name, id)
+		}
+	}
+	return nil
+}
+
+// Usage: {{BeDuration()}}, e.g. "1h30m" (see https://golang.org/pkg/time/#ParseDuration)
+func newBeDurationMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 0, 0); err != nil {
+		return nil, err
+	}
+	return NewFormatMatcher("a duration", func(s string) error {
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("must be a sequence of numbers with units ns, us, ms, s, m or h, e.g. 1h30m")
+		}
+		return nil
+	}), nil
+}
@@ -0,0 +1,102 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatFunctions(t *testing.T) {
+	cases := []struct {
+		input  string
+		actual string
+		// reason is part of the failure message, empty if actual should match
+		reason string
+	}{
+		{"{{BeUUID()}}", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", ""},
+		{"{{BeUUID()}}", "6ba7b810-9dad-11d1-80b4", "must have 36 characters but has 23"},
+		{"{{BeUUID()}}", "6ba7b810x9dad-11d1-80b4-00c04fd430c8", "character 9 must be '-'"},
+		{"{{BeUUID()}}", "6ba7b810-9dad-11d1-80b4-00c04fd430cg", "character 36 must be a hex digit"},
+		{"{{BeUUID(v4)}}", "f47ac10b-58cc-4372-a567-0e02b2c3d479", ""},
+		{"{{BeUUID(v4)}}", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "version must be 4 but is 1"},
+		{"{{BeUUID(v4)}}", "f47ac10b-58cc-4372-c567-0e02b2c3d479", "variant must be RFC 4122"},
+		{"{{BeEmail()}}", "ethan.hunt@imf.gov", ""},
+		{"{{BeEmail()}}", "ethan.hunt", "must contain '@'"},
+		{"{{BeEmail()}}", "@imf.gov", "local part (before '@') must not be empty"},
+		{"{{BeEmail()}}", "ethan@localhost", "domain localhost must contain '.'"},
+		{"{{BeEmail()}}", "Ethan <ethan@imf.gov>", "must be a plain address"},
+		{"{{BeURL()}}", "https://imf.gov/agents?id=1", ""},
+		{"{{BeURL()}}", "imf.gov/agents", "scheme is missing"},
+		{"{{BeURL()}}", "https:///agents", "host is missing"},
+		{"{{BeURL(https)}}", "http://imf.gov", "scheme must be one of https but is http"},
+		{"{{BeIP()}}", "10.0.0.1", ""},
+		{"{{BeIP()}}", "::1", ""},
+		{"{{BeIP()}}", "10.0.0.256", "must be a dotted decimal"},
+		{"{{BeIP(v4)}}", "::1", "must be an IPv4 address but is IPv6"},
+		{"{{BeIP(v6)}}", "10.0.0.1", "must be an IPv6 address but is IPv4"},
+		{"{{BeCIDR()}}", "10.0.0.0/8", ""},
+		{"{{BeCIDR()}}", "10.0.0.0", "must contain '/'"},
+		{"{{BeCIDR()}}", "10.0.0.0/33", "prefix length 33 is invalid"},
+		{"{{BeBase64()}}", "aGVsbG8=", ""},
+		{"{{BeBase64()}}", "aGVsbG8", "length must be a multiple of 4"},
+		{"{{BeBase64()}}", "aGV*bG8=", "character 4 ('*') is not in the alphabet"},
+		{"{{BeBase64()}}", "QQ=\n", "is truncated, padding is incomplete"},
+		{"{{BeBase64()}}", "Q=Q=", "padding '=' at character 2 is misplaced"},
+		{"{{BeBase64(url)}}", "-_-_", ""},
+		{"{{BeSemver()}}", "1.2.3-beta.1+build.5", ""},
+		{"{{BeSemver()}}", "1.2", "must have the form MAJOR.MINOR.PATCH"},
+		{"{{BeSemver()}}", "1.02.3", "minor version 02 must not have leading zeros"},
+		{"{{BeSemver()}}", "1.2.3-beta..1", "pre-release must not have empty identifiers"},
+		{"{{BeSemver()}}", "v1.2.3", "major version \"v1\" must be a number"},
+		{"{{BeDuration()}}", "1h30m", ""},
+		{"{{BeDuration()}}", "90", "must be a sequence of numbers with units"},
+	}
+	for _, c := range cases {
+		matcher, err := CreateStringMatcher(c.input)
+		if err != nil {
+			t.Fatalf("%s: err should be nil but was %+v", c.input, err)
+		}
+		matched, err := matcher.Match(c.actual)
+		if err != nil {
+			t.Fatalf("%s: err should be nil but was %+v", c.input, err)
+		}
+		if c.reason == "" {
+			if !matched {
+				t.Fatalf("%s: %s should match but failed with %s", c.input, c.actual, matcher.FailureMessage(c.actual))
+			}
+			continue
+		}
+		if matched {
+			t.Fatalf("%s: %s should not match", c.input, c.actual)
+		}
+		if msg := matcher.FailureMessage(c.actual); !strings.Contains(msg, c.reason) {
+			t.Fatalf("%s: failure message should contain %q but was %s", c.input, c.reason, msg)
+		}
+	}
+}
+
+func TestFormatFunctions_InvalidArgs(t *testing.T) {
+	for _, input := range []string{"{{BeUUID(v7)}}", "{{BeIP(v5)}}", "{{BeBase64(std, url)}}", "{{BeEmail(a)}}"} {
+		if _, err := CreateStringMatcher(input); err == nil {
+			t.Fatalf("%s: err should not be nil", input)
+		}
+	}
+}
+
+func TestFormatFunctions_Walk(t *testing.T) {
+	exp := Node{
+		Type:  Object,
+		Value: []byte(`{"id": "{{BeUUID(v4)}}", "ip": "{{Not(BeIP())}}"}`),
+	}
+	act := Node{
+		Type:  Object,
+		Value: []byte(`{"id": "not-a-uuid", "ip": "unknown"}`),
+	}
+	matcher, matched, _ := Walk("", exp, act, JSONParserInstance)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	fm := matcher.(*FailureMatcher)
+	if fm.Path != ".id" || !strings.Contains(fm.Reason, "to be a UUID v4: must have 36 characters but has 10") {
+		t.Fatalf("failure incorrect, path = %s, reason = %s", fm.Path, fm.Reason)
+	}
+}
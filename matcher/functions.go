@@ -0,0 +1,245 @@
+package matcher
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/onsi/gomega/format"
+	"github.com/onsi/gomega/matchers"
+	"github.com/onsi/gomega/types"
+)
+
+var (
+	functionsMu sync.RWMutex
+	functions   = map[string]Function{}
+)
+
+func init() {
+	RegisterFunction("BeEmpty", newBeEmptyMatcher)
+	RegisterFunction("BeNumerically", newBeNumericallyMatcher)
+	RegisterFunction("BeTimestamp", newBeTimestampMatcher)
+	RegisterFunction("HaveKey", newHaveKeyMatcher)
+	RegisterFunction("HaveKeys", newHaveKeysMatcher)
+	RegisterFunction("HaveLen", newHaveLenMatcher)
+	RegisterFunction("BeOneOf", newBeOneOfMatcher)
+}
+
+// RegisterFunction registers f so that it can be used in all golden files as {{name(args)}}.  Registering a name
+// again replaces the function.
+//
+// Functions passed to a matcher (e.g. with gosert.WithFunctions) take precedence over registered functions.
+func RegisterFunction(name string, f Function) {
+	functionsMu.Lock()
+	defer functionsMu.Unlock()
+	functions[name] = f
+}
+
+// LookupFunction returns the registered function with name.
+func LookupFunction(name string) (Function, bool) {
+	functionsMu.RLock()
+	defer functionsMu.RUnlock()
+	f, ok := functions[name]
+	return f, ok
+}
+
+// CreateFunctionMatcher returns a matcher for a function call, e.g. {{BeEmpty()}} or {{Not(BeEmpty())}}.  local
+// functions take precedence over registered functions.  Returns false if input is not a call to a known function.
+//
+// Not is a built-in combinator, its argument must be another function call without braces, e.g. Not(BeEmpty()).
+func CreateFunctionMatcher(input string, local map[string]Function) (types.GomegaMatcher, bool, error) {
+	m := patternFunction.FindStringSubmatch(input)
+	if m == nil || patternLiteral.MatchString(input) {
+		return nil, false, nil
+	}
+	name, args := m[1], splitArgs(m[2])
+	if name == "Not" {
+		if len(args) != 1 {
+			return nil, true, fmt.Errorf("Not expects 1 argument but got %d", len(args))
+		}
+		matcher, ok, err := CreateFunctionMatcher("{{"+args[0]+"}}", local)
+		if !ok || err != nil {
+			return nil, ok, err
+		}
+		return &matchers.NotMatcher{
+			Matcher: matcher,
+		}, true, nil
+	}
+	f, ok := local[name]
+	if !ok {
+		f, ok = LookupFunction(name)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	matcher, err := f(args)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %s", name, err.Error())
+	}
+	return matcher, true, nil
+}
+
+// UseFunction returns a function that looks up a matcher by name, for use as {{Use(name)}}.
+func UseFunction(matchers map[string]types.GomegaMatcher) Function {
+	return func(args []string) (types.GomegaMatcher, error) {
+		if err := expectArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		m, ok := matchers[args[0]]
+		if !ok {
+			return nil, fmt.Errorf("matcher %s is not defined", args[0])
+		}
+		return m, nil
+	}
+}
+
+func expectArgs(args []string, min, max int) error {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return fmt.Errorf("expects %d arguments but got %d", min, len(args))
+		}
+		return fmt.Errorf("expects %d to %d arguments but got %d", min, max, len(args))
+	}
+	return nil
+}
+
+// Usage: {{BeEmpty()}}, which means the string/array/object must be empty
+func newBeEmptyMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 0, 0); err != nil {
+		return nil, err
+	}
+	return &matchers.BeEmptyMatcher{}, nil
+}
+
+// Usage: {{BeNumerically(~, 123.23, 0.5)}}, which means 123.23 +/- 0.5
+func newBeNumericallyMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 2, 3); err != nil {
+		return nil, err
+	}
+	var flts []interface{}
+	for _, arg := range args[1:] {
+		flt, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, err
+		}
+		flts = append(flts, flt)
+	}
+	return &matchers.BeNumericallyMatcher{
+		Comparator: args[0],
+		CompareTo:  flts,
+	}, nil
+}
+
+// Usage: {{BeTimestamp(2018-01-02T12:13:14.123Z, 5000)}}, which means 2018-01-02T12:13:14.123Z +/- 5000 milliseconds,
+// or {{BeTimestamp()}}, which means any timestamp
+func newBeTimestampMatcher(args []string) (types.GomegaMatcher, error) {
+	if len(args) == 0 {
+		return NewFormatMatcher("a timestamp", func(s string) error {
+			if _, err := ParseTime(s); err != nil {
+				return fmt.Errorf("must have RFC 3339 format, e.g. 2018-10-05T12:13:14.123Z")
+			}
+			return nil
+		}), nil
+	}
+	if err := expectArgs(args, 2, 2); err != nil {
+		return nil, err
+	}
+	ts, err := ParseTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	delta, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return NewTimestampMatcher(ts, time.Duration(delta)*time.Millisecond), nil
+}
+
+// Usage: {{HaveKey(userId)}}, which means the object must have the key
+func newHaveKeyMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	return &matchers.HaveKeyMatcher{
+		Key: args[0],
+	}, nil
+}
+
+// Usage: {{HaveKeys(userId, name)}}, which means the object must have all the keys
+func newHaveKeysMatcher(args []string) (types.GomegaMatcher, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expects at least 1 argument")
+	}
+	var ms []types.GomegaMatcher
+	for _, arg := range args {
+		ms = append(ms, &matchers.HaveKeyMatcher{
+			Key: arg,
+		})
+	}
+	return &matchers.AndMatcher{
+		Matchers: ms,
+	}, nil
+}
+
+// Usage: {{HaveLen(3)}}, which means the string/array/object must have 3 characters/elements/keys
+func newHaveLenMatcher(args []string) (types.GomegaMatcher, error) {
+	if err := expectArgs(args, 1, 1); err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return &matchers.HaveLenMatcher{
+		Count: count,
+	}, nil
+}
+
+// OneOfMatcher matches strings, numbers and booleans with a list of values.
+type OneOfMatcher struct {
+	Values []string
+}
+
+// Usage: {{BeOneOf(ex, unclear)}}, which means the value must be "ex" or "unclear"
+func newBeOneOfMatcher(args []string) (types.GomegaMatcher, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expects at least 1 argument")
+	}
+	return &OneOfMatcher{
+		Values: args,
+	}, nil
+}
+
+// Match matches a `string`, `float64` or `bool`.
+func (matcher *OneOfMatcher) Match(actual interface{}) (bool, error) {
+	for _, v := range matcher.Values {
+		switch a := actual.(type) {
+		case string:
+			if a == v {
+				return true, nil
+			}
+		case float64:
+			if f, err := strconv.ParseFloat(v, 64); err == nil && f == a {
+				return true, nil
+			}
+		case bool:
+			if b, err := strconv.ParseBool(v); err == nil && b == a {
+				return true, nil
+			}
+		default:
+			return false, fmt.Errorf("OneOfMatcher expects a string, number or boolean")
+		}
+	}
+	return false, nil
+}
+
+// FailureMessage returns failure message.
+func (matcher *OneOfMatcher) FailureMessage(actual interface{}) string {
+	return format.Message(actual, "to be one of", matcher.Values)
+}
+
+// NegatedFailureMessage returns negated failure message.
+func (matcher *OneOfMatcher) NegatedFailureMessage(actual interface{}) string {
+	return format.Message(actual, "not to be one of", matcher.Values)
+}
@@ -0,0 +1,202 @@
+package matcher
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega/matchers"
+	"github.com/onsi/gomega/types"
+)
+
+func newHavePrefixMatcher(args []string) (types.GomegaMatcher, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expects 1 argument but got %d", len(args))
+	}
+	return &matchers.HavePrefixMatcher{
+		Prefix: args[0],
+	}, nil
+}
+
+func TestRegisterFunction(t *testing.T) {
+	RegisterFunction("HavePrefixTest", newHavePrefixMatcher)
+
+	if _, ok := LookupFunction("HavePrefixTest"); !ok {
+		t.Fatalf("HavePrefixTest should be registered")
+	}
+	if _, ok := LookupFunction("NoSuchFunction"); ok {
+		t.Fatalf("NoSuchFunction should not be registered")
+	}
+
+	matcher, err := CreateStringMatcher("{{HavePrefixTest(EUR)}}")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if matched, _ := matcher.Match("EUR-123"); !matched {
+		t.Fatalf("EUR-123 should match")
+	}
+	if matched, _ := matcher.Match("USD-123"); matched {
+		t.Fatalf("USD-123 should not match")
+	}
+
+	matcher, err = CreateStringMatcher("{{Not(HavePrefixTest(EUR))}}")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if matched, _ := matcher.Match("USD-123"); !matched {
+		t.Fatalf("USD-123 should match")
+	}
+
+	_, err = CreateStringMatcher("{{HavePrefixTest(EUR, USD)}}")
+	if err == nil || !strings.Contains(err.Error(), "HavePrefixTest: expects 1 argument") {
+		t.Fatalf("err incorrect, %+v", err)
+	}
+
+	// Unknown functions are plain strings
+	matcher, err = CreateStringMatcher("{{NoSuchFunction(EUR)}}")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if matched, _ := matcher.Match("{{NoSuchFunction(EUR)}}"); !matched {
+		t.Fatalf("unknown function should be compared as a string")
+	}
+}
+
+func TestCreateFunctionMatcher_LocalPrecedence(t *testing.T) {
+	RegisterFunction("HavePrefixTest", newHavePrefixMatcher)
+	local := map[string]Function{
+		"HavePrefixTest": func(args []string) (types.GomegaMatcher, error) {
+			return &matchers.HaveSuffixMatcher{
+				Suffix: args[0],
+			}, nil
+		},
+	}
+
+	w := NewWalker(JSONParserInstance)
+	w.Functions = local
+	exp := Node{
+		Type:  String,
+		Value: []byte("{{HavePrefixTest(EUR)}}"),
+	}
+	act := Node{
+		Type:  String,
+		Value: []byte("123-EUR"),
+	}
+	matcher, matched, err := w.Walk("", exp, act)
+	if matcher != SuccessMatcherInstance {
+		t.Fatalf("matcher should be SuccessMatcherInstance but was %+v, matched = %t, err = %+v", matcher, matched, err)
+	}
+
+	matcher, matched, err = Walk("", exp, act, JSONParserInstance)
+	if matched {
+		t.Fatalf("matched should be false, registered function should be used without local functions")
+	}
+	fm, ok := matcher.(*FailureMatcher)
+	if !ok {
+		t.Fatalf("matcher should be *FailureMatcher but was %+v", matcher)
+	}
+	if !strings.Contains(fm.Reason, "to have prefix") {
+		t.Fatalf("Reason incorrect, %s", fm.Reason)
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	cases := []struct {
+		input   string
+		actual  interface{}
+		matched bool
+	}{
+		{"{{BeEmpty()}}", "", true},
+		{"{{BeEmpty()}}", []interface{}{1.0}, false},
+		{"{{Not(BeEmpty())}}", "a", true},
+		{"{{BeNumerically(~, 123, 0.5)}}", 123.4, true},
+		{"{{BeNumerically(>=, 123)}}", 122.0, false},
+		{"{{BeTimestamp(2018-10-05T12:13:14.000Z, 5000)}}", "2018-10-05T12:13:18.000Z", true},
+		{"{{BeTimestamp(2018-10-05T12:13:14.000Z, 5000)}}", "2018-10-05T12:13:20.000Z", false},
+		{"{{BeTimestamp()}}", "2018-10-05T12:13:20.000Z", true},
+		{"{{BeTimestamp()}}", "2018-10-05", false},
+	}
+	for _, c := range cases {
+		matcher, ok, err := CreateFunctionMatcher(c.input, nil)
+		if !ok || err != nil {
+			t.Fatalf("%s: ok should be true and err nil but were %t, %+v", c.input, ok, err)
+		}
+		matched, _ := matcher.Match(c.actual)
+		if matched != c.matched {
+			t.Fatalf("%s: matched should be %t for %v", c.input, c.matched, c.actual)
+		}
+	}
+
+	_, ok, err := CreateFunctionMatcher("{{BeNumerically(~, abc)}}", nil)
+	if !ok || err == nil {
+		t.Fatalf("invalid argument should be an error")
+	}
+}
+
+func TestWalk_ObjectFunctions(t *testing.T) {
+	cases := []struct {
+		exp     string
+		matched bool
+	}{
+		{"{{HaveKey(u1)}}", true},
+		{"{{HaveKey(u3)}}", false},
+		{"{{HaveKeys(u1, u2)}}", true},
+		{"{{HaveKeys(u1, u3)}}", false},
+		{"{{HaveLen(2)}}", true},
+		{"{{HaveLen(3)}}", false},
+		{"{{BeEmpty()}}", false},
+		{"{{Not(BeEmpty())}}", true},
+	}
+	for _, c := range cases {
+		exp := Node{
+			Type:  Object,
+			Value: []byte(`{"users": "` + c.exp + `"}`),
+		}
+		act := Node{
+			Type:  Object,
+			Value: []byte(`{"users": {"u1": {"name": "Ethan"}, "u2": {"name": "Ilsa"}}}`),
+		}
+		_, matched, err := Walk("", exp, act, JSONParserInstance)
+		if matched != c.matched || err != nil {
+			t.Fatalf("%s: matched should be %t but was %t, err = %+v", c.exp, c.matched, matched, err)
+		}
+	}
+
+	exp := Node{
+		Type:  Object,
+		Value: []byte(`{"users": "{{BeEmpty()}}", "teams": "IMF"}`),
+	}
+	act := Node{
+		Type:  Object,
+		Value: []byte(`{"users": {}, "teams": {"t1": "IMF"}}`),
+	}
+	w := NewWalker(JSONParserInstance)
+	w.ContinueOnFailure = true
+	_, matched, _ := w.Walk("", exp, act)
+	if matched || len(w.Failures()) != 1 {
+		t.Fatalf("there should be 1 failure but was %+v", w.Failures())
+	}
+	if f := w.Failures()[0]; f.Path != ".teams" || f.Err == nil {
+		t.Fatalf("failure incorrect, %+v", f)
+	}
+}
+
+func TestBeOneOf(t *testing.T) {
+	matcher, err := CreateStringMatcher("{{BeOneOf(ex, 1, true)}}")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	for _, actual := range []interface{}{"ex", 1.0, true} {
+		if matched, err := matcher.Match(actual); !matched || err != nil {
+			t.Fatalf("%v should match, err = %+v", actual, err)
+		}
+	}
+	for _, actual := range []interface{}{"unclear", 2.0, false} {
+		if matched, _ := matcher.Match(actual); matched {
+			t.Fatalf("%v should not match", actual)
+		}
+	}
+	if _, err := matcher.Match([]interface{}{}); err == nil {
+		t.Fatalf("err should not be nil for arrays")
+	}
+}
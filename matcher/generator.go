@@ -0,0 +1,99 @@
+package matcher
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// Usage: @now, @now+5m, @now-1h30m
+	patternGenNow = regexp.MustCompile(`^now(?:(?P<sign>[+-])(?P<offset>.+))?$`)
+	// Usage: @rand(1,100), which is an integer in [1, 100]
+	patternGenRand = regexp.MustCompile(`^rand\(\s*(?P<min>-?\d+)\s*,\s*(?P<max>-?\d+)\s*\)$`)
+)
+
+// TimestampFormat is the format used for generated timestamps.  It is accepted by {{BeTimestamp(...)}}.
+const TimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Generator evaluates built-in generator variables such as ${{@now}}, ${{@uuid}} and ${{@rand(1,100)}}.
+//
+// Each distinct expression is evaluated once and the value is reused, so every reference to ${{@uuid}} sees the same
+// UUID.  Two generators created with the same seed and time produce the same values.
+type Generator struct {
+	now    time.Time
+	rand   *rand.Rand
+	values map[string]string
+}
+
+// NewGenerator returns a new *Generator.  seed is used for random values and now is the value of @now.
+func NewGenerator(seed int64, now time.Time) *Generator {
+	return &Generator{
+		now:    now,
+		rand:   rand.New(rand.NewSource(seed)),
+		values: map[string]string{},
+	}
+}
+
+// Value returns the value of expr (without the leading "@").  Returns an error if expr is not a known generator.
+func (g *Generator) Value(expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if v, ok := g.values[expr]; ok {
+		return v, nil
+	}
+	v, err := g.generate(expr)
+	if err != nil {
+		return "", err
+	}
+	g.values[expr] = v
+	return v, nil
+}
+
+func (g *Generator) generate(expr string) (string, error) {
+	if expr == "uuid" {
+		return g.uuid(), nil
+	}
+	if patternGenNow.MatchString(expr) {
+		sub := mapSubexpNames(patternGenNow.FindStringSubmatch(expr), patternGenNow.SubexpNames())
+		t := g.now
+		if sub["offset"] != "" {
+			d, err := time.ParseDuration(sub["offset"])
+			if err != nil {
+				return "", fmt.Errorf("generator '@%s' has invalid offset: %s", expr, err.Error())
+			}
+			if sub["sign"] == "-" {
+				d = -d
+			}
+			t = t.Add(d)
+		}
+		return t.UTC().Format(TimestampFormat), nil
+	}
+	if patternGenRand.MatchString(expr) {
+		sub := mapSubexpNames(patternGenRand.FindStringSubmatch(expr), patternGenRand.SubexpNames())
+		min, err := strconv.ParseInt(sub["min"], 10, 64)
+		if err != nil {
+			return "", err
+		}
+		max, err := strconv.ParseInt(sub["max"], 10, 64)
+		if err != nil {
+			return "", err
+		}
+		if max < min {
+			return "", fmt.Errorf("generator '@%s' must have min <= max", expr)
+		}
+		return strconv.FormatInt(min+g.rand.Int63n(max-min+1), 10), nil
+	}
+	return "", fmt.Errorf("generator '@%s' is not supported.  See Gosert doc.", expr)
+}
+
+// uuid returns a random (version 4) UUID.
+func (g *Generator) uuid() string {
+	b := make([]byte, 16)
+	g.rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,78 @@
+package matcher
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGenerator_Value(t *testing.T) {
+	now := time.Date(2018, 10, 5, 12, 13, 14, 123000000, time.UTC)
+	gen := NewGenerator(1, now)
+
+	ts, err := gen.Value("now")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if ts != "2018-10-05T12:13:14.123Z" {
+		t.Fatalf("@now incorrect, %s", ts)
+	}
+	ts, err = gen.Value("now+5m")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if ts != "2018-10-05T12:18:14.123Z" {
+		t.Fatalf("@now+5m incorrect, %s", ts)
+	}
+	ts, err = gen.Value("now-1h")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if ts != "2018-10-05T11:13:14.123Z" {
+		t.Fatalf("@now-1h incorrect, %s", ts)
+	}
+
+	id, err := gen.Value("uuid")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Fatalf("@uuid incorrect, %s", id)
+	}
+	again, _ := gen.Value("uuid")
+	if again != id {
+		t.Fatalf("@uuid should be evaluated once, was %s and %s", id, again)
+	}
+
+	r, err := gen.Value("rand(1, 100)")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	n, err := strconv.Atoi(r)
+	if err != nil || n < 1 || n > 100 {
+		t.Fatalf("@rand(1, 100) incorrect, %s", r)
+	}
+}
+
+func TestGenerator_Value_Seed(t *testing.T) {
+	now := time.Now()
+	gen1 := NewGenerator(42, now)
+	gen2 := NewGenerator(42, now)
+	for _, expr := range []string{"uuid", "rand(0, 1000000)"} {
+		v1, _ := gen1.Value(expr)
+		v2, _ := gen2.Value(expr)
+		if v1 != v2 {
+			t.Fatalf("@%s should be the same for the same seed, was %s and %s", expr, v1, v2)
+		}
+	}
+}
+
+func TestGenerator_Value_Failure(t *testing.T) {
+	gen := NewGenerator(1, time.Now())
+	for _, expr := range []string{"foo", "now+5x", "rand(10, 1)"} {
+		if _, err := gen.Value(expr); err == nil {
+			t.Fatalf("err should not be nil for @%s", expr)
+		}
+	}
+}
@@ -21,9 +21,15 @@ func (p *JSONParser) ValidateObject(data []byte) error {
 func (p *JSONParser) GetFields(data []byte) map[string]Node {
 	m := map[string]Node{}
 	jsonparser.ObjectEach(data, func(key []byte, value []byte, dataType jsonparser.ValueType, offset int) error {
+		// offset is the end of the value, after the closing quote of strings
+		start := offset - len(value)
+		if dataType == jsonparser.String {
+			start--
+		}
 		m[string(key)] = Node{
-			Type:  jsonparserToInternal(dataType),
-			Value: value,
+			Type:   jsonparserToInternal(dataType),
+			Value:  value,
+			Offset: start,
 		}
 		return nil
 	})
@@ -37,17 +43,34 @@ func (p *JSONParser) GetArray(data []byte) []Node {
 		if err != nil {
 			log.Printf("Error parsing array: %+v", err)
 		}
+		// offset is the start of the value, or of the closing quote of strings
+		start := offset
+		if dataType == jsonparser.String {
+			start--
+		}
 		nodes = append(nodes, Node{
-			Type:  jsonparserToInternal(dataType),
-			Value: value,
+			Type:   jsonparserToInternal(dataType),
+			Value:  value,
+			Offset: start,
 		})
 	})
 	return nodes
 }
 
-// Delete implements Parser.
+// Delete implements Parser.  data is not modified.
 func (p *JSONParser) Delete(data []byte, key string) []byte {
-	return jsonparser.Delete(data, key)
+	// jsonparser.Delete modifies data in place, which would corrupt the expected value for later matches
+	return jsonparser.Delete(append([]byte(nil), data...), key)
+}
+
+// Unescape implements Unescaper.
+func (p *JSONParser) Unescape(value []byte) ([]byte, error) {
+	return jsonparser.Unescape(value, nil)
+}
+
+// Locates implements Locator.
+func (p *JSONParser) Locates(data []byte) bool {
+	return true
 }
 
 func jsonparserToInternal(dataType jsonparser.ValueType) ValueType {
@@ -0,0 +1,28 @@
+package matcher
+
+import (
+	"testing"
+)
+
+func TestJSONParser_Offsets(t *testing.T) {
+	data := []byte(`{"foo": "bar", "baz": [1, "q\"x", {"a": true}], "e": ""}`)
+	fields := JSONParserInstance.GetFields(data)
+	cases := map[string]int{
+		"foo": 9,
+		"baz": 22,
+		"e":   54,
+	}
+	for k, expected := range cases {
+		f := fields[k]
+		if f.Offset != expected || string(data[f.Offset:f.Offset+len(f.Value)]) != string(f.Value) {
+			t.Fatalf("offset of %s should be %d but was %d", k, expected, f.Offset)
+		}
+	}
+
+	baz := fields["baz"]
+	for i, e := range JSONParserInstance.GetArray(baz.Value) {
+		if string(baz.Value[e.Offset:e.Offset+len(e.Value)]) != string(e.Value) {
+			t.Fatalf("offset of element %d incorrect: %d", i, e.Offset)
+		}
+	}
+}
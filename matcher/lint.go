@@ -0,0 +1,192 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onsi/gomega/types"
+)
+
+// Placeholder is a value for variables that are not known when linting, e.g. "{{BeTimestamp(${{?}}, 1000)}}".  Lint
+// does not check the arguments of functions containing Placeholder.
+const Placeholder = "${{?}}"
+
+// LintError is a problem in an expected value found by Lint.
+type LintError struct {
+	// Path is the path of the problem, as reported by Walk
+	Path    string
+	Message string
+}
+
+// Error implements error.
+func (e LintError) Error() string {
+	return fmt.Sprintf("%s: %s", displayPath(e.Path), e.Message)
+}
+
+// Lint statically checks the expected value exp for problems that would otherwise only surface when matching, e.g.
+// unknown functions, bad arguments, bad markers or arrays mixing '_gst_index' and '_gst_id'.  functions are custom
+// functions (see Walker.Functions).
+func Lint(exp Node, parser Parser, functions map[string]Function) []LintError {
+	l := &linter{
+		parser:    parser,
+		functions: map[string]Function{},
+	}
+	// Use refers to matchers that are only known when matching
+	l.functions["Use"] = func(args []string) (types.GomegaMatcher, error) {
+		return SuccessMatcherInstance, expectArgs(args, 1, 1)
+	}
+	for name, f := range functions {
+		l.functions[name] = f
+	}
+	l.lint("", exp)
+	return l.errs
+}
+
+type linter struct {
+	parser    Parser
+	functions map[string]Function
+	errs      []LintError
+}
+
+func (l *linter) add(path, format string, args ...interface{}) {
+	l.errs = append(l.errs, LintError{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) lint(path string, exp Node) {
+	switch exp.Type {
+	case String:
+		l.lintString(path, string(exp.Value))
+	case Object:
+		l.lintObject(path, exp)
+	case Array:
+		l.lintArray(path, l.parser.GetArray(exp.Value))
+	}
+}
+
+func (l *linter) lintString(path, s string) {
+	if patternLiteral.MatchString(s) {
+		return
+	}
+	if m := patternDecoder.FindStringSubmatch(s); m != nil && strings.HasPrefix(s, "{{") {
+		for m != nil {
+			s = m[2]
+			m = patternDecoder.FindStringSubmatch(s)
+		}
+		if trimmed := strings.TrimSpace(s); strings.HasPrefix(trimmed, "[") || (strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "{{")) {
+			// Nested (escaped) document
+			return
+		}
+		l.lintString(path, s)
+		return
+	}
+	if m := patternEach.FindStringSubmatch(s); m != nil {
+		l.lintString(path, m[1])
+		return
+	}
+	m := patternFunction.FindStringSubmatch(s)
+	if m == nil {
+		if strings.HasPrefix(s, "{{") {
+			l.add(path, "'%s' looks like a function but is malformed, use {{Literal(...)}} for literal text", s)
+		}
+		return
+	}
+	_, ok, err := CreateFunctionMatcher(s, l.functions)
+	if !ok {
+		l.add(path, "unknown function in '%s'", s)
+		return
+	}
+	if err != nil && !strings.Contains(s, Placeholder) {
+		l.add(path, "invalid arguments in '%s': %s", s, err.Error())
+	}
+}
+
+func (l *linter) lintObject(path string, exp Node) {
+	if err := l.parser.ValidateObject(exp.Value); err != nil {
+		l.add(path, "invalid object: %s", err.Error())
+		return
+	}
+	fields := l.parser.GetFields(exp.Value)
+	if marker, ok := fields[KeyEmbedded]; ok {
+		if _, err := embeddedDecoders(marker); err != nil {
+			l.add(path, "%s", err.Error())
+			return
+		}
+		path += "|" + string(marker.Value)
+		delete(fields, KeyEmbedded)
+	}
+	for _, k := range sortedKeys(fields) {
+		if m := patternEachKey.FindStringSubmatch(k); m != nil {
+			if _, err := regexp.Compile(m[1]); err != nil {
+				l.add(path+"."+k, "invalid regex in '%s': %s", k, err.Error())
+				continue
+			}
+		}
+		l.lint(path+"."+k, fields[k])
+	}
+}
+
+func (l *linter) lintArray(path string, exp []Node) {
+	each, rest := splitEachExpectations(exp, l.parser)
+	for _, e := range each {
+		l.lint(path+"[0]", e)
+	}
+	if len(rest) == 0 || IsBaseTypes(rest) {
+		return
+	}
+	if !IsObjects(rest) {
+		l.add(path, "array type assertion must all be base data type or all object type, not a mixture of both")
+		return
+	}
+	if !hasArrayMarkers(rest, l.parser) {
+		// Not an expectation, e.g. a fixture in a multipart file
+		return
+	}
+	isByIndex, err := isArrayExpectedByIndex(rest, l.parser)
+	if err != nil {
+		l.add(path, "%s", err.Error())
+		return
+	}
+	if isByIndex {
+		expMap, err := createExpectedIndexMapper(rest, l.parser)
+		if err != nil {
+			l.add(path, "%s", err.Error())
+			return
+		}
+		var indexes []int
+		for i := range expMap {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		for _, i := range indexes {
+			l.lint(path+"["+strconv.Itoa(i)+"]", expMap[i])
+		}
+		return
+	}
+	metaKey, expMap, err := createExpectedIDMapper(rest, l.parser)
+	if err != nil {
+		l.add(path, "%s", err.Error())
+		return
+	}
+	for _, k := range sortedKeys(expMap) {
+		l.lint(path+"."+metaKey+"="+k, expMap[k])
+	}
+}
+
+func hasArrayMarkers(nodes []Node, parser Parser) bool {
+	for _, node := range nodes {
+		fields := parser.GetFields(node.Value)
+		if _, ok := fields[KeyIndex]; ok {
+			return true
+		}
+		if _, ok := fields[KeyID]; ok {
+			return true
+		}
+	}
+	return false
+}
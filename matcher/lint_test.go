@@ -0,0 +1,60 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/onsi/gomega/types"
+)
+
+func TestLint(t *testing.T) {
+	exp := `{
+		"id": "{{BeUUID(v9)}}",
+		"name": "{{BeNice()}}",
+		"nick": "{{Not(BeEmpty())}}",
+		"bio": "{{Literal({{BeNice()}})}}",
+		"title": "{{Broken(}}",
+		"createdAt": "{{BeTimestamp(${{?}}, 1000)}}",
+		"custom": "{{Custom(a, b)}}",
+		"blob": "{{Base64(Gzip({{BeNice()}}))}}",
+		"tags": "{{Each({{HaveLen(a)}})}}",
+		"payload": {"_gst_embedded": "rot13|json", "id": "0001"},
+		"body": {"_gst_embedded": "base64|json", "id": "{{BeNice()}}"},
+		"_gst_each_key([)": "{{BeEmpty()}}",
+		"connections": [
+			{"_gst_index": 0, "id": "0002"},
+			{"_gst_id": "id=0003"}
+		],
+		"users": [
+			{"_gst_id": "id=0001", "role": "{{BeOneOf()}}"}
+		],
+		"items": [{"_gst_each": {"sku": "{{BeNice()}}"}}],
+		"mixed": ["a", {"b": "c"}],
+		"fixture": [{"id": "0001"}, {"id": "0002"}]
+	}`
+	functions := map[string]Function{
+		"Custom": func(args []string) (types.GomegaMatcher, error) {
+			return SuccessMatcherInstance, expectArgs(args, 1, 1)
+		},
+	}
+	var msgs []string
+	for _, e := range Lint(Node{Type: Object, Value: []byte(exp)}, JSONParserInstance, functions) {
+		msgs = append(msgs, e.Error())
+	}
+	expected := `._gst_each_key([): invalid regex in '_gst_each_key([)': error parsing regexp: missing closing ]: ` + "`[`" + `
+.blob: unknown function in '{{BeNice()}}'
+.body|base64|json.id: unknown function in '{{BeNice()}}'
+.connections: cannot have some elements with '_gst_index' and some with '_gst_id'
+.custom: invalid arguments in '{{Custom(a, b)}}': Custom: expects 1 arguments but got 2
+.id: invalid arguments in '{{BeUUID(v9)}}': BeUUID: version must be one of v1 to v5 but was v9
+.items[0].sku: unknown function in '{{BeNice()}}'
+.mixed: array type assertion must all be base data type or all object type, not a mixture of both
+.name: unknown function in '{{BeNice()}}'
+.payload: unknown decoder 'rot13' in '_gst_embedded' field
+.tags: invalid arguments in '{{HaveLen(a)}}': HaveLen: strconv.Atoi: parsing "a": invalid syntax
+.title: '{{Broken(}}' looks like a function but is malformed, use {{Literal(...)}} for literal text
+.users.id=0001.role: invalid arguments in '{{BeOneOf()}}': BeOneOf: expects at least 1 argument`
+	if strings.Join(msgs, "\n") != expected {
+		t.Fatalf("errors incorrect\n%s", strings.Join(msgs, "\n"))
+	}
+}
@@ -26,15 +26,44 @@ func (matcher *SuccessMatcher) NegatedFailureMessage(actual interface{}) (messag
 	return ""
 }
 
+// FailureKind describes why a match failed.
+type FailureKind string
+
+const (
+	// FailureMismatch means the actual value does not match the expected value.
+	FailureMismatch = FailureKind("mismatch")
+	// FailureMissing means the actual value is missing.
+	FailureMissing = FailureKind("missing")
+	// FailureType means the actual value has a different type from the expected value.
+	FailureType = FailureKind("type")
+	// FailureUnexpected means the actual value has a field that is not expected.
+	FailureUnexpected = FailureKind("unexpected")
+	// FailureError means the match could not be done, e.g., the assertion is malformed.
+	FailureError = FailureKind("error")
+)
+
 // FailureMatcher always fails.  It is used to report custom error messages.
 type FailureMatcher struct {
-	Message string
+	Message  string
+	Path     string
+	Expected string
+	Actual   string
+	Kind     FailureKind
+	Err      error
+	// Reason is an optional explanation of the failure, e.g. from a custom function
+	Reason string
+	// Location is where the expected value is defined in the golden file, e.g. "golden.json:12:5"
+	Location string
 }
 
 // NewFailureMatcher returns a new *FailureMatcher.
 func NewFailureMatcher(path, expected, actual string) *FailureMatcher {
 	return &FailureMatcher{
-		Message: fmt.Sprintf("path = %s, expected = %s, actual = %s", path, expected, actual),
+		Message:  fmt.Sprintf("path = %s, expected = %s, actual = %s", path, truncate(expected, MaxDiffLineLength), truncate(actual, MaxDiffLineLength)),
+		Path:     path,
+		Expected: expected,
+		Actual:   actual,
+		Kind:     FailureMismatch,
 	}
 }
 
@@ -43,12 +72,26 @@ func (matcher *FailureMatcher) Match(actual interface{}) (success bool, err erro
 	return false, nil
 }
 
-// FailureMessage implements types.GomegaMatcher.
+// FailureMessage implements types.GomegaMatcher.  The message contains a diff of the expected and actual values.
 func (matcher *FailureMatcher) FailureMessage(actual interface{}) (message string) {
-	return matcher.Message
+	if matcher.Expected == matcher.Actual {
+		return matcher.summary()
+	}
+	return fmt.Sprintf("%s\n%s", matcher.summary(), Diff(matcher.Path, matcher.Expected, matcher.Actual))
 }
 
 // NegatedFailureMessage implements types.GomegaMatcher.
 func (matcher *FailureMatcher) NegatedFailureMessage(actual interface{}) (message string) {
-	return fmt.Sprintf("Not %s", matcher.Message)
+	return fmt.Sprintf("Not %s", matcher.summary())
+}
+
+func (matcher *FailureMatcher) summary() string {
+	s := matcher.Message
+	if matcher.Location != "" {
+		s = fmt.Sprintf("%s, at %s", s, matcher.Location)
+	}
+	if matcher.Reason != "" {
+		s = fmt.Sprintf("%s\n%s", s, matcher.Reason)
+	}
+	return s
 }
@@ -0,0 +1,470 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/onsi/gomega/types"
+)
+
+func init() {
+	RegisterFunction("MatchSchema", MatchSchemaFunction(".", JSONParserInstance))
+}
+
+var (
+	schemasMu sync.Mutex
+	// schemas caches parsed schema files by path, so that MatchSchema doesn't read the file on every match
+	schemas = map[string]cachedSchema{}
+)
+
+// cachedSchema is a parsed schema file, which is reloaded if the file is modified.
+type cachedSchema struct {
+	schema  *Schema
+	modTime time.Time
+	size    int64
+}
+
+// Schema is a JSON Schema.  The following subset of draft-07/2020-12 is supported:
+//
+//	type, enum, const, required, properties, additionalProperties, items, minItems, maxItems, pattern, minLength,
+//	maxLength, minimum, maximum, exclusiveMinimum, exclusiveMaximum, allOf, anyOf, oneOf, not
+//
+// Other keywords are ignored.
+type Schema struct {
+	Type                 schemaTypes        `json:"type"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                *interface{}       `json:"const"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *Schema            `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Pattern              string             `json:"pattern"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum"`
+	AllOf                []*Schema          `json:"allOf"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+	Not                  *Schema            `json:"not"`
+
+	// never is true for the schema `false`, which matches nothing
+	never   bool
+	pattern *regexp.Regexp
+}
+
+// schemaTypes is the "type" keyword, either a string or an array of strings.
+type schemaTypes []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = schemaTypes{s}
+		return nil
+	}
+	var a []string
+	if err := json.Unmarshal(data, &a); err != nil {
+		return fmt.Errorf("'type' must be a string or an array of strings")
+	}
+	*t = a
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  It supports the boolean schemas `true` and `false`.
+func (s *Schema) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = Schema{never: !b}
+		return nil
+	}
+	// Alias has no methods, to avoid recursion
+	type alias Schema
+	var a alias
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*s = Schema(a)
+	if s.Const == nil {
+		// encoding/json leaves the pointer nil for "const": null, which must only allow null
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err == nil {
+			if _, ok := fields["const"]; ok {
+				s.Const = new(interface{})
+			}
+		}
+	}
+	if s.Pattern != "" {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %s: %s", s.Pattern, err.Error())
+		}
+		s.pattern = pattern
+	}
+	return nil
+}
+
+// ParseSchema parses a JSON Schema.
+func ParseSchema(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %s", err.Error())
+	}
+	return s, nil
+}
+
+// ParseSchemaFile parses a JSON Schema file.
+func ParseSchemaFile(path string) (*Schema, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	key := filepath.Clean(path)
+	schemasMu.Lock()
+	cached, ok := schemas[key]
+	schemasMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.schema, nil
+	}
+
+	s, err := parseSchemaFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schemasMu.Lock()
+	schemas[key] = cachedSchema{
+		schema:  s,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+	schemasMu.Unlock()
+	return s, nil
+}
+
+func parseSchemaFile(path string) (*Schema, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ParseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err.Error())
+	}
+	return s, nil
+}
+
+// SchemaError is a violation of a schema at a path.
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+// Error implements error.
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", displayPath(e.Path), e.Message)
+}
+
+// Validate returns all violations of s by node.  path is the path of node, e.g. ".connections[0]".
+func (s *Schema) Validate(path string, node Node, parser Parser) []SchemaError {
+	var errs []SchemaError
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, SchemaError{
+			Path:    path,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	if s.never {
+		fail("no value is allowed")
+		return errs
+	}
+
+	typ := schemaType(node)
+	if len(s.Type) > 0 && !s.Type.allows(typ) {
+		fail("type must be %s but is %s", strings.Join(s.Type, " or "), typ)
+		return errs
+	}
+	if len(s.Enum) > 0 || s.Const != nil {
+		value := schemaValue(node, parser)
+		if s.Const != nil && !reflect.DeepEqual(value, *s.Const) {
+			fail("value must be %s", rawJSON(*s.Const))
+		}
+		if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+			fail("value must be one of %s", rawJSON(s.Enum))
+		}
+	}
+
+	switch typ {
+	case "string":
+		str, err := unescape(node, parser)
+		if err != nil {
+			str = node.Value
+		}
+		length := utf8.RuneCount(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("length must be >= %d but is %d", *s.MinLength, length)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("length must be <= %d but is %d", *s.MaxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.Match(str) {
+			fail("value must match pattern %s", s.Pattern)
+		}
+	case "integer", "number":
+		f, _ := toNumber(node.Value)
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("value must be >= %v but is %v", *s.Minimum, f)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("value must be <= %v but is %v", *s.Maximum, f)
+		}
+		if s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum {
+			fail("value must be > %v but is %v", *s.ExclusiveMinimum, f)
+		}
+		if s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum {
+			fail("value must be < %v but is %v", *s.ExclusiveMaximum, f)
+		}
+	case "object":
+		fields := parser.GetFields(node.Value)
+		for _, k := range s.Required {
+			if _, ok := fields[k]; !ok {
+				errs = append(errs, SchemaError{
+					Path:    path + "." + k,
+					Message: "field is required",
+				})
+			}
+		}
+		for _, k := range sortedKeys(fields) {
+			if p, ok := s.Properties[k]; ok {
+				errs = append(errs, p.Validate(path+"."+k, fields[k], parser)...)
+			} else if s.AdditionalProperties != nil {
+				if s.AdditionalProperties.never {
+					errs = append(errs, SchemaError{
+						Path:    path + "." + k,
+						Message: "field is not allowed",
+					})
+					continue
+				}
+				errs = append(errs, s.AdditionalProperties.Validate(path+"."+k, fields[k], parser)...)
+			}
+		}
+	case "array":
+		elements := parser.GetArray(node.Value)
+		if s.MinItems != nil && len(elements) < *s.MinItems {
+			fail("must have >= %d items but has %d", *s.MinItems, len(elements))
+		}
+		if s.MaxItems != nil && len(elements) > *s.MaxItems {
+			fail("must have <= %d items but has %d", *s.MaxItems, len(elements))
+		}
+		if s.Items != nil {
+			for i, e := range elements {
+				errs = append(errs, s.Items.Validate(path+"["+strconv.Itoa(i)+"]", e, parser)...)
+			}
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		errs = append(errs, sub.Validate(path, node, parser)...)
+	}
+	if len(s.AnyOf) > 0 && s.countValid(s.AnyOf, path, node, parser) == 0 {
+		fail("value must match at least one schema in anyOf")
+	}
+	if len(s.OneOf) > 0 {
+		if n := s.countValid(s.OneOf, path, node, parser); n != 1 {
+			fail("value must match exactly one schema in oneOf but matches %d", n)
+		}
+	}
+	if s.Not != nil && len(s.Not.Validate(path, node, parser)) == 0 {
+		fail("value must not match the schema in not")
+	}
+	return errs
+}
+
+func (s *Schema) countValid(schemas []*Schema, path string, node Node, parser Parser) int {
+	n := 0
+	for _, sub := range schemas {
+		if len(sub.Validate(path, node, parser)) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (t schemaTypes) allows(typ string) bool {
+	for _, allowed := range t {
+		if allowed == typ || (allowed == "number" && typ == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaType returns the JSON Schema type of node.
+func schemaType(node Node) string {
+	switch node.Type {
+	case String:
+		return "string"
+	case Number:
+		if f, err := toNumber(node.Value); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case Boolean:
+		return "boolean"
+	case Object:
+		return "object"
+	case Array:
+		return "array"
+	}
+	return "null"
+}
+
+// schemaValue returns node decoded like encoding/json does, for comparison with enum and const.
+func schemaValue(node Node, parser Parser) interface{} {
+	if node.Type == String {
+		if str, err := unescape(node, parser); err == nil {
+			return string(str)
+		}
+	}
+	if node.Type == Null {
+		return nil
+	}
+	return Decode(node, parser)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func rawJSON(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(bs)
+}
+
+// NodeMatcher is implemented by matchers that match nodes rather than decoded values.  When a function returns a
+// NodeMatcher, Walk calls MatchNode instead of Match.
+type NodeMatcher interface {
+	types.GomegaMatcher
+	// MatchNode matches node at path.
+	MatchNode(path string, node Node, parser Parser) (bool, error)
+}
+
+// SchemaMatcher matches documents with a JSON Schema.
+type SchemaMatcher struct {
+	Schema *Schema
+	Parser Parser
+
+	errs []SchemaError
+}
+
+// NewSchemaMatcher returns a new *SchemaMatcher.  Documents passed to Match are parsed with parser.
+func NewSchemaMatcher(schema *Schema, parser Parser) *SchemaMatcher {
+	return &SchemaMatcher{
+		Schema: schema,
+		Parser: parser,
+	}
+}
+
+// MatchSchemaFunction returns the MatchSchema function, which resolves relative schema paths against dir and parses
+// documents with parser.  Schema files are parsed once and cached.
+//
+// Usage: {{MatchSchema(path/to/schema.json)}}.  The registered function resolves paths against the working directory,
+// gosert matchers resolve them against the directory of the golden file.
+func MatchSchemaFunction(dir string, parser Parser) Function {
+	return func(args []string) (types.GomegaMatcher, error) {
+		if err := expectArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		path := args[0]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		schema, err := ParseSchemaFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewSchemaMatcher(schema, parser), nil
+	}
+}
+
+// Match matches a document (`string` or `[]byte`) parsed with matcher.Parser, or a decoded value (see Decode), e.g.
+// when negated with Not.
+func (matcher *SchemaMatcher) Match(actual interface{}) (bool, error) {
+	switch a := actual.(type) {
+	case string:
+		return matcher.MatchNode("", DocumentNode([]byte(a)), matcher.Parser)
+	case []byte:
+		return matcher.MatchNode("", DocumentNode(a), matcher.Parser)
+	}
+	data, err := json.Marshal(map[string]interface{}{"value": actual})
+	if err != nil {
+		return false, fmt.Errorf("SchemaMatcher expects a string, []byte or decoded value: %s", err.Error())
+	}
+	node := JSONParserInstance.GetFields(data)["value"]
+	return matcher.MatchNode("", node, JSONParserInstance)
+}
+
+// MatchNode implements NodeMatcher.
+func (matcher *SchemaMatcher) MatchNode(path string, node Node, parser Parser) (bool, error) {
+	matcher.errs = matcher.Schema.Validate(path, node, parser)
+	return len(matcher.errs) == 0, nil
+}
+
+// Errors returns the violations found by the last match.
+func (matcher *SchemaMatcher) Errors() []SchemaError {
+	return matcher.errs
+}
+
+// FailureMessage returns failure message.
+func (matcher *SchemaMatcher) FailureMessage(actual interface{}) string {
+	var lines []string
+	for _, e := range matcher.errs {
+		lines = append(lines, "  "+e.Error())
+	}
+	sort.Strings(lines)
+	return fmt.Sprintf("Expected value to match the schema, but found %d violations:\n%s", len(matcher.errs), strings.Join(lines, "\n"))
+}
+
+// NegatedFailureMessage returns negated failure message.
+func (matcher *SchemaMatcher) NegatedFailureMessage(actual interface{}) string {
+	return "Expected value not to match the schema"
+}
+
+// DocumentNode returns the root node of a document, an Object or an Array.
+func DocumentNode(data []byte) Node {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		return Node{
+			Type:  Array,
+			Value: data,
+		}
+	}
+	return Node{
+		Type:  Object,
+		Value: data,
+	}
+}
@@ -0,0 +1,137 @@
+package matcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"required": ["id", "tags"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"type": "string", "pattern": "^[a-z]+$", "maxLength": 5},
+			"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+			"score": {"type": ["number", "null"]},
+			"kind": {"enum": ["a", "b", 1]},
+			"version": {"const": 2},
+			"tags": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+			"contact": {
+				"oneOf": [
+					{"type": "object", "required": ["email"]},
+					{"type": "object", "required": ["phone"]}
+				]
+			},
+			"ref": {"anyOf": [{"type": "string"}, {"type": "integer"}], "not": {"const": "none"}}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+
+	valid := `{"id": "abc", "age": 36, "score": null, "kind": 1, "version": 2, "tags": ["x"], "contact": {"email": "e"}, "ref": 3}`
+	if errs := schema.Validate("", DocumentNode([]byte(valid)), JSONParserInstance); len(errs) != 0 {
+		t.Fatalf("there should be no errors but were %+v", errs)
+	}
+
+	invalid := `{"id": "ABCDEFG", "age": 36.5, "score": "1", "kind": "c", "version": 3, "tags": ["x", ""],
+		"contact": {"email": "e", "phone": "p"}, "ref": "none", "extra": true}`
+	var msgs []string
+	for _, e := range schema.Validate("", DocumentNode([]byte(invalid)), JSONParserInstance) {
+		msgs = append(msgs, e.Error())
+	}
+	expected := []string{
+		".age: type must be integer but is number",
+		".contact: value must match exactly one schema in oneOf but matches 2",
+		".extra: field is not allowed",
+		".id: length must be <= 5 but is 7",
+		".id: value must match pattern ^[a-z]+$",
+		".kind: value must be one of [\"a\",\"b\",1]",
+		".ref: value must not match the schema in not",
+		".score: type must be number or null but is string",
+		".tags[1]: length must be >= 1 but is 0",
+		".version: value must be 2",
+	}
+	if strings.Join(msgs, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("errors incorrect\n%s", strings.Join(msgs, "\n"))
+	}
+
+	if errs := schema.Validate("", DocumentNode([]byte(`{"tags": []}`)), JSONParserInstance); len(errs) != 2 ||
+		errs[0].Error() != ".id: field is required" || errs[1].Error() != ".tags: must have >= 1 items but has 0" {
+		t.Fatalf("errors incorrect, %+v", errs)
+	}
+}
+
+func TestParseSchema_Invalid(t *testing.T) {
+	if _, err := ParseSchema([]byte(`{"pattern": "[a-"}`)); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Fatalf("err incorrect, %+v", err)
+	}
+	if _, err := ParseSchema([]byte(`{"type": 1}`)); err == nil {
+		t.Fatalf("err should not be nil")
+	}
+}
+
+func TestSchemaMatcher(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"type": "array", "items": {"type": "integer"}}`))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	m := NewSchemaMatcher(schema, JSONParserInstance)
+	if matched, err := m.Match(" [1, 2]"); !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+	if matched, _ := m.Match([]byte(`[1, "2"]`)); matched {
+		t.Fatalf("matched should be false")
+	}
+	if msg := m.FailureMessage(nil); !strings.Contains(msg, "[1]: type must be integer but is string") {
+		t.Fatalf("failure message incorrect, %s", msg)
+	}
+}
+
+func TestSchema_ConstNull(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{"properties": {"deletedAt": {"const": null}}}`))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if errs := schema.Validate("", DocumentNode([]byte(`{"deletedAt": null}`)), JSONParserInstance); len(errs) != 0 {
+		t.Fatalf("there should be no errors but were %+v", errs)
+	}
+	if errs := schema.Validate("", DocumentNode([]byte(`{"deletedAt": 0}`)), JSONParserInstance); len(errs) != 1 ||
+		errs[0].Error() != ".deletedAt: value must be null" {
+		t.Fatalf("errors incorrect, %+v", errs)
+	}
+}
+
+func TestParseSchemaFile_Cache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gosert")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "schema.json")
+	if err := ioutil.WriteFile(path, []byte(`{"type": "string"}`), 0644); err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+
+	first, err := ParseSchemaFile(path)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	second, err := ParseSchemaFile(path)
+	if err != nil || second != first {
+		t.Fatalf("the schema should be cached, err = %+v", err)
+	}
+
+	// Modified files are reloaded
+	if err := ioutil.WriteFile(path, []byte(`{"type": "integer"}`), 0644); err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	third, err := ParseSchemaFile(path)
+	if err != nil || third == first || third.Type[0] != "integer" {
+		t.Fatalf("the schema should be reloaded, err = %+v", err)
+	}
+}
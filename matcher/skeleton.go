@@ -0,0 +1,535 @@
+package matcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// idKeyCandidates are field names that are likely IDs, in order of preference.  Fields ending with "Id", "ID" or
+// "_id" are also likely IDs.
+var idKeyCandidates = []string{"id", "key", "code", "name"}
+
+// Skeleton returns a golden value that matches all samples (JSON objects or arrays parsed with parser).  Values that
+// are the same in all samples are copied.  Other values, and values that look volatile (timestamps, UUIDs), are
+// replaced by functions, e.g. {{BeTimestamp()}} or {{Not(BeEmpty())}}.  Object arrays are matched by ID if a field
+// that looks like an ID is found, otherwise with _gst_each.
+func Skeleton(samples [][]byte, parser Parser) ([]byte, error) {
+	values, err := decodeSamples(samples, parser)
+	if err != nil {
+		return nil, err
+	}
+	skel, ok := skeletonValue(values)
+	if !ok {
+		return nil, fmt.Errorf("samples do not have the same type")
+	}
+	return encodeOrdered(skel)
+}
+
+// InferSchema returns a JSON Schema that validates all samples (JSON objects or arrays parsed with parser).
+func InferSchema(samples [][]byte, parser Parser) ([]byte, error) {
+	values, err := decodeSamples(samples, parser)
+	if err != nil {
+		return nil, err
+	}
+	schema := orderedObject{{key: "$schema", value: "https://json-schema.org/draft/2020-12/schema"}}
+	return encodeOrdered(append(schema, inferSchema(values)...))
+}
+
+// orderedObject is a JSON object with keys in order.
+type orderedObject []orderedField
+
+type orderedField struct {
+	key   string
+	value interface{}
+}
+
+// function is a DSL function, e.g. {{Not(BeEmpty())}}
+type function string
+
+func decodeSamples(samples [][]byte, parser Parser) ([]interface{}, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("at least 1 sample is required")
+	}
+	var values []interface{}
+	for i, sample := range samples {
+		node := DocumentNode(sample)
+		if node.Type == Object {
+			if err := parser.ValidateObject(node.Value); err != nil {
+				return nil, fmt.Errorf("sample %d: %s", i+1, err.Error())
+			}
+		}
+		values = append(values, decodeSample(node, parser))
+	}
+	return values, nil
+}
+
+// decodeSample decodes node, with strings unescaped.
+func decodeSample(node Node, parser Parser) interface{} {
+	switch node.Type {
+	case String, Null:
+		return schemaValue(node, parser)
+	case Object:
+		m := map[string]interface{}{}
+		for k, v := range parser.GetFields(node.Value) {
+			m[k] = decodeSample(v, parser)
+		}
+		return m
+	case Array:
+		a := []interface{}{}
+		for _, v := range parser.GetArray(node.Value) {
+			a = append(a, decodeSample(v, parser))
+		}
+		return a
+	}
+	return Decode(node, parser)
+}
+
+func kindOf(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return "null"
+}
+
+// sameKind returns the kind of values, or "" if they have different kinds.
+func sameKind(values []interface{}) string {
+	kind := kindOf(values[0])
+	for _, v := range values[1:] {
+		if kindOf(v) != kind {
+			return ""
+		}
+	}
+	return kind
+}
+
+func allEqual(values []interface{}) bool {
+	first := canonical(values[0])
+	for _, v := range values[1:] {
+		if canonical(v) != first {
+			return false
+		}
+	}
+	return true
+}
+
+// canonical returns v encoded as JSON, with array elements sorted since order is ignored when matching.
+func canonical(v interface{}) string {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		var fields []string
+		for _, k := range sortedStringKeys(t) {
+			fields = append(fields, strconv.Quote(k)+":"+canonical(t[k]))
+		}
+		return "{" + strings.Join(fields, ",") + "}"
+	case []interface{}:
+		var elements []string
+		for _, e := range t {
+			elements = append(elements, canonical(e))
+		}
+		sort.Strings(elements)
+		return "[" + strings.Join(elements, ",") + "]"
+	}
+	bs, _ := json.Marshal(v)
+	return string(bs)
+}
+
+func sortedStringKeys(m map[string]interface{}) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func allMatch(values []interface{}, input string) bool {
+	for _, v := range values {
+		m, _, err := CreateFunctionMatcher(input, nil)
+		if err != nil {
+			return false
+		}
+		if matched, err := m.Match(v); !matched || err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// skeletonValue returns the golden value that matches all values.  Returns false if there is none.
+func skeletonValue(values []interface{}) (interface{}, bool) {
+	kind := sameKind(values)
+	switch kind {
+	case "string":
+		for _, f := range []string{"{{BeUUID(v4)}}", "{{BeUUID()}}", "{{BeTimestamp()}}"} {
+			if allMatch(values, f) {
+				return function(f), true
+			}
+		}
+		if allEqual(values) {
+			// Variables are escaped when written, other braces are kept with Literal
+			if str := values[0].(string); strings.Contains(patternSubstitution.ReplaceAllString(str, ""), "{{") {
+				return function("{{Literal(" + str + ")}}"), true
+			}
+			return values[0], true
+		}
+		if allMatch(values, "{{Not(BeEmpty())}}") {
+			return function("{{Not(BeEmpty())}}"), true
+		}
+	case "number":
+		if allEqual(values) {
+			return values[0], true
+		}
+		min := math.Inf(1)
+		for _, v := range values {
+			min = math.Min(min, v.(float64))
+		}
+		return function(fmt.Sprintf("{{BeNumerically(>=, %s)}}", formatNumber(min))), true
+	case "boolean":
+		if allEqual(values) {
+			return values[0], true
+		}
+		return function("{{BeOneOf(true, false)}}"), true
+	case "null":
+		return nil, true
+	case "object":
+		return skeletonObject(values), true
+	case "array":
+		return skeletonArray(values)
+	}
+	return nil, false
+}
+
+func skeletonObject(values []interface{}) orderedObject {
+	var obj orderedObject
+	first := values[0].(map[string]interface{})
+	for _, k := range sortedStringKeys(first) {
+		var fieldValues []interface{}
+		for _, v := range values {
+			if fv, ok := v.(map[string]interface{})[k]; ok {
+				fieldValues = append(fieldValues, fv)
+			}
+		}
+		if len(fieldValues) < len(values) {
+			// Optional field
+			continue
+		}
+		if skel, ok := skeletonValue(fieldValues); ok {
+			obj = append(obj, orderedField{key: k, value: skel})
+		}
+	}
+	return obj
+}
+
+func skeletonArray(values []interface{}) (interface{}, bool) {
+	var arrays [][]interface{}
+	var elements []interface{}
+	empty := 0
+	for _, v := range values {
+		a := v.([]interface{})
+		arrays = append(arrays, a)
+		elements = append(elements, a...)
+		if len(a) == 0 {
+			empty++
+		}
+	}
+	if empty == len(values) {
+		return function("{{BeEmpty()}}"), true
+	}
+	kind := sameKind(elements)
+	if kind != "object" && kind != "array" && allEqual(values) {
+		return values[0], true
+	}
+	if kind == "" {
+		if empty == 0 {
+			return function("{{Not(BeEmpty())}}"), true
+		}
+		return nil, false
+	}
+	if kind == "object" {
+		if key := inferIDKey(arrays); key != "" {
+			if byID := skeletonByID(key, arrays); len(byID) > 0 {
+				return byID, true
+			}
+		}
+	}
+	each, ok := skeletonValue(elements)
+	if !ok {
+		return nil, false
+	}
+	return []interface{}{orderedObject{{key: KeyEach, value: each}}}, true
+}
+
+// skeletonByID returns an element with _gst_id for every ID that is in all arrays.
+func skeletonByID(key string, arrays [][]interface{}) []interface{} {
+	var result []interface{}
+	for _, e := range arrays[0] {
+		id := e.(map[string]interface{})[key]
+		var group []interface{}
+		for _, a := range arrays {
+			for _, other := range a {
+				if canonical(other.(map[string]interface{})[key]) == canonical(id) {
+					group = append(group, other)
+				}
+			}
+		}
+		if len(group) < len(arrays) {
+			continue
+		}
+		idValue := fmt.Sprintf("%v", id)
+		if f, ok := id.(float64); ok {
+			idValue = formatNumber(f)
+		}
+		obj := orderedObject{{key: KeyID, value: key + "=" + idValue}}
+		result = append(result, append(obj, skeletonObject(group)...))
+	}
+	return result
+}
+
+// inferIDKey returns a field that is in all elements, has unique values in each array and looks like an ID.
+// Returns "" if there is none.
+func inferIDKey(arrays [][]interface{}) string {
+	counts := map[string]int{}
+	total := 0
+	for _, a := range arrays {
+		for _, e := range a {
+			total++
+			for k, v := range e.(map[string]interface{}) {
+				if kind := kindOf(v); kind == "string" || kind == "number" {
+					counts[k]++
+				}
+			}
+		}
+	}
+	var candidates []string
+	for k, n := range counts {
+		if n == total && isIDKey(k) && isUniqueAndStable(k, arrays) {
+			candidates = append(candidates, k)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ri, rj := idKeyRank(candidates[i]), idKeyRank(candidates[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return candidates[i] < candidates[j]
+	})
+	return candidates[0]
+}
+
+func isIDKey(k string) bool {
+	return idKeyRank(k) < len(idKeyCandidates)+1
+}
+
+func idKeyRank(k string) int {
+	for i, c := range idKeyCandidates {
+		if k == c {
+			return i
+		}
+	}
+	if strings.HasSuffix(k, "Id") || strings.HasSuffix(k, "ID") || strings.HasSuffix(k, "_id") {
+		return len(idKeyCandidates)
+	}
+	return len(idKeyCandidates) + 1
+}
+
+// isUniqueAndStable returns true if the values of k are unique in each array, and don't look volatile.
+func isUniqueAndStable(k string, arrays [][]interface{}) bool {
+	for _, a := range arrays {
+		seen := map[string]bool{}
+		for _, e := range a {
+			v := e.(map[string]interface{})[k]
+			if allMatch([]interface{}{v}, "{{BeUUID()}}") || allMatch([]interface{}{v}, "{{BeTimestamp()}}") {
+				return false
+			}
+			c := canonical(v)
+			if seen[c] {
+				return false
+			}
+			seen[c] = true
+		}
+	}
+	return true
+}
+
+// inferSchema returns the schema that validates all values.
+func inferSchema(values []interface{}) orderedObject {
+	var kinds []string
+	byKind := map[string][]interface{}{}
+	for _, v := range values {
+		kind := kindOf(v)
+		if kind == "number" && v.(float64) == math.Trunc(v.(float64)) {
+			kind = "integer"
+		}
+		if _, ok := byKind[kind]; !ok {
+			kinds = append(kinds, kind)
+		}
+		byKind[kind] = append(byKind[kind], v)
+	}
+	if _, ok := byKind["number"]; ok {
+		if integers, ok := byKind["integer"]; ok {
+			byKind["number"] = append(byKind["number"], integers...)
+			delete(byKind, "integer")
+			kinds = removeString(kinds, "integer")
+		}
+	}
+	sort.Strings(kinds)
+
+	var schema orderedObject
+	if len(kinds) == 1 {
+		schema = append(schema, orderedField{key: "type", value: kinds[0]})
+	} else {
+		schema = append(schema, orderedField{key: "type", value: kinds})
+	}
+	if strs, ok := byKind["string"]; ok {
+		if allMatch(strs, "{{BeUUID()}}") {
+			schema = append(schema, orderedField{key: "format", value: "uuid"})
+		} else if allMatch(strs, "{{BeTimestamp()}}") {
+			schema = append(schema, orderedField{key: "format", value: "date-time"})
+		}
+	}
+	if objs, ok := byKind["object"]; ok {
+		counts := map[string]int{}
+		fieldValues := map[string][]interface{}{}
+		for _, o := range objs {
+			for k, v := range o.(map[string]interface{}) {
+				counts[k]++
+				fieldValues[k] = append(fieldValues[k], v)
+			}
+		}
+		var keys, required []string
+		for k, n := range counts {
+			keys = append(keys, k)
+			if n == len(objs) {
+				required = append(required, k)
+			}
+		}
+		sort.Strings(keys)
+		sort.Strings(required)
+		if len(required) > 0 {
+			schema = append(schema, orderedField{key: "required", value: required})
+		}
+		var properties orderedObject
+		for _, k := range keys {
+			properties = append(properties, orderedField{key: k, value: inferSchema(fieldValues[k])})
+		}
+		schema = append(schema, orderedField{key: "properties", value: properties})
+	}
+	if arrays, ok := byKind["array"]; ok {
+		var elements []interface{}
+		for _, a := range arrays {
+			elements = append(elements, a.([]interface{})...)
+		}
+		if len(elements) > 0 {
+			schema = append(schema, orderedField{key: "items", value: inferSchema(elements)})
+		}
+	}
+	return schema
+}
+
+func removeString(list []string, s string) []string {
+	var result []string
+	for _, e := range list {
+		if e != s {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// encodeOrdered returns v encoded as indented JSON.
+func encodeOrdered(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeOrdered(&buf, v); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+func writeOrdered(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case orderedObject:
+		buf.WriteString("{")
+		for i, f := range t {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeJSON(buf, escapeVariables(f.key)); err != nil {
+				return err
+			}
+			buf.WriteString(":")
+			if err := writeOrdered(buf, f.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("}")
+		return nil
+	case []interface{}:
+		buf.WriteString("[")
+		for i, e := range t {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if err := writeOrdered(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("]")
+		return nil
+	case map[string]interface{}:
+		var obj orderedObject
+		for _, k := range sortedStringKeys(t) {
+			obj = append(obj, orderedField{key: k, value: t[k]})
+		}
+		return writeOrdered(buf, obj)
+	case function:
+		v = string(t)
+	case string:
+		v = escapeVariables(t)
+	}
+	return writeJSON(buf, v)
+}
+
+// escapeVariables escapes variables in s, e.g. ${{MY_VAR}} becomes $${{MY_VAR}}, so that s is unchanged by Replace.
+func escapeVariables(s string) string {
+	return patternSubstitution.ReplaceAllStringFunc(s, func(m string) string {
+		return "$" + m
+	})
+}
+
+// writeJSON writes v encoded as JSON.  HTML characters are not escaped, since golden strings are compared as is.
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+	return nil
+}
@@ -0,0 +1,161 @@
+package matcher
+
+import (
+	"testing"
+)
+
+var skeletonSamples = [][]byte{
+	[]byte(`{
+		"userId": "0001",
+		"requestId": "f47ac10b-58cc-4372-a567-0e02b2c3d479",
+		"name": "Ethan Hunt",
+		"age": 36,
+		"active": true,
+		"note": "a <b>",
+		"template": "Hello {{name}}",
+		"interests": ["climbing", "jogging"],
+		"connections": [
+			{"id": "0002", "name": "Julia Meade", "since": 2010},
+			{"id": "0003", "name": "Ilsa Faust", "since": 2015}
+		],
+		"events": [{"at": "2018-10-05T12:13:14.123Z", "type": "login"}],
+		"updatedAt": "2018-10-05T12:13:14.123Z"
+	}`),
+	[]byte(`{
+		"userId": "0002",
+		"requestId": "9b2d3a4e-1c2b-4d5e-8f6a-7b8c9d0e1f2a",
+		"name": "Ethan Hunt",
+		"age": 37,
+		"active": false,
+		"note": "a <b>",
+		"template": "Hello {{name}}",
+		"interests": ["jogging", "climbing"],
+		"connections": [
+			{"id": "0003", "name": "Ilsa Faust", "since": 2015, "extra": 1},
+			{"id": "0004", "name": "Benji Dunn", "since": 2011}
+		],
+		"events": [],
+		"updatedAt": "2018-10-06T12:13:14.123Z",
+		"optional": "x"
+	}`),
+}
+
+func TestSkeleton(t *testing.T) {
+	golden, err := Skeleton(skeletonSamples, JSONParserInstance)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	expected := `{
+  "active": "{{BeOneOf(true, false)}}",
+  "age": "{{BeNumerically(>=, 36)}}",
+  "connections": [
+    {
+      "_gst_id": "id=0003",
+      "id": "0003",
+      "name": "Ilsa Faust",
+      "since": 2015
+    }
+  ],
+  "events": [
+    {
+      "_gst_each": {
+        "at": "{{BeTimestamp()}}",
+        "type": "login"
+      }
+    }
+  ],
+  "interests": [
+    "climbing",
+    "jogging"
+  ],
+  "name": "Ethan Hunt",
+  "note": "a <b>",
+  "requestId": "{{BeUUID(v4)}}",
+  "template": "{{Literal(Hello {{name}})}}",
+  "updatedAt": "{{BeTimestamp()}}",
+  "userId": "{{Not(BeEmpty())}}"
+}
+`
+	if string(golden) != expected {
+		t.Fatalf("skeleton incorrect\n%s", golden)
+	}
+
+	// The skeleton must match all samples
+	for i, sample := range skeletonSamples {
+		w := NewWalker(JSONParserInstance)
+		w.ContinueOnFailure = true
+		if _, matched, err := w.Walk("", Node{Type: Object, Value: golden}, Node{Type: Object, Value: sample}); !matched {
+			t.Fatalf("sample %d should match, err = %+v, failures = %+v", i, err, w.Failures())
+		}
+	}
+}
+
+func TestSkeleton_Variables(t *testing.T) {
+	samples := [][]byte{
+		[]byte(`{"${{KEY}}": "a ${{VALUE}} b", "escaped": "$${{VALUE}}", "template": "${{VALUE}} {{name}}", "items": [{"id": "${{ID}}"}]}`),
+	}
+	golden, err := Skeleton(samples, JSONParserInstance)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	expected := `{
+  "$${{KEY}}": "a $${{VALUE}} b",
+  "escaped": "$$${{VALUE}}",
+  "items": [
+    {
+      "_gst_id": "id=$${{ID}}",
+      "id": "$${{ID}}"
+    }
+  ],
+  "template": "{{Literal(${{VALUE}} {{name}})}}"
+}
+`
+	if string(golden) != expected {
+		t.Fatalf("skeleton incorrect\n%s", golden)
+	}
+
+	// Variables in samples are text, the skeleton must match without vars
+	replaced, err := Replace(golden, nil)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	w := NewWalker(JSONParserInstance)
+	w.ContinueOnFailure = true
+	if _, matched, err := w.Walk("", Node{Type: Object, Value: replaced}, Node{Type: Object, Value: samples[0]}); !matched {
+		t.Fatalf("sample should match, err = %+v, failures = %+v", err, w.Failures())
+	}
+}
+
+func TestSkeleton_Errors(t *testing.T) {
+	if _, err := Skeleton(nil, JSONParserInstance); err == nil {
+		t.Fatalf("err should not be nil for no samples")
+	}
+	if _, err := Skeleton([][]byte{[]byte(`{"a": `)}, JSONParserInstance); err == nil {
+		t.Fatalf("err should not be nil for invalid samples")
+	}
+	if _, err := Skeleton([][]byte{[]byte(`{}`), []byte(`[]`)}, JSONParserInstance); err == nil {
+		t.Fatalf("err should not be nil for samples with different types")
+	}
+}
+
+func TestInferSchema(t *testing.T) {
+	data, err := InferSchema(skeletonSamples, JSONParserInstance)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	schema, err := ParseSchema(data)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	for i, sample := range skeletonSamples {
+		if errs := schema.Validate("", DocumentNode(sample), JSONParserInstance); len(errs) > 0 {
+			t.Fatalf("sample %d should be valid but was %+v", i, errs)
+		}
+	}
+	if errs := schema.Validate("", DocumentNode([]byte(`{"userId": 1}`)), JSONParserInstance); len(errs) == 0 {
+		t.Fatalf("there should be errors")
+	}
+	if len(schema.Required) != 11 || schema.Properties["requestId"] == nil || schema.Properties["age"].Type[0] != "integer" {
+		t.Fatalf("schema incorrect\n%s", data)
+	}
+}
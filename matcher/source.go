@@ -0,0 +1,258 @@
+package matcher
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Locator is implemented by parsers that can tell where values returned by GetFields and GetArray are in data, see
+// Node.Offset.
+type Locator interface {
+	// Locates returns true if Node.Offset is set for values in data.
+	Locates(data []byte) bool
+}
+
+// Position is a position in a golden file.
+type Position struct {
+	File string
+	Line int
+	Col  int
+}
+
+// String returns string, e.g. "golden.json:12:5".
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+}
+
+// Source is a golden document with variables replaced.  It keeps track of where the document came from, so that
+// positions in Data can be mapped to positions in the golden file.
+type Source struct {
+	// Name is the name of the golden file
+	Name string
+	// Data is the document with variables replaced
+	Data []byte
+
+	original []byte
+	lines    []int
+	subs     []substitution
+}
+
+// NewSource returns a new *Source with variables in data replaced.  lines maps each line in data to a line in the
+// file (1-based).  If lines is nil, data is the whole file.  Generator variables are evaluated with gen unless vars has
+// a value for them, e.g. "@now".
+func NewSource(name string, data []byte, lines []int, vars map[string]string, gen *Generator) (*Source, error) {
+	replaced, subs, err := replace(data, vars, gen)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{
+		Name:     name,
+		Data:     replaced,
+		original: data,
+		lines:    lines,
+		subs:     subs,
+	}, nil
+}
+
+// Position returns the position in the golden file of offset in Data.  Offsets inside a replaced variable are mapped
+// to the start of the variable.
+func (s *Source) Position(offset int) Position {
+	return s.OriginalPosition(s.originalOffset(offset))
+}
+
+// originalOffset returns the offset in the document before variables were replaced of offset in Data.
+func (s *Source) originalOffset(offset int) int {
+	orig := offset
+	for _, sub := range s.subs {
+		if sub.start > offset {
+			break
+		}
+		if offset < sub.end {
+			orig = sub.origStart
+		} else {
+			orig = sub.origEnd + offset - sub.end
+		}
+	}
+	return orig
+}
+
+// Splice replaces Data[start:end] with data, e.g. to include another document.  Offsets in data are mapped to the
+// position of start, like offsets in replaced variables.
+func (s *Source) Splice(start, end int, data []byte) {
+	sub := substitution{
+		start:     start,
+		end:       start + len(data),
+		origStart: s.originalOffset(start),
+		origEnd:   s.originalOffset(end),
+	}
+	delta := len(data) - (end - start)
+	var subs []substitution
+	for _, old := range s.subs {
+		if old.end <= start {
+			subs = append(subs, old)
+		}
+	}
+	subs = append(subs, sub)
+	for _, old := range s.subs {
+		if old.start >= end {
+			old.start += delta
+			old.end += delta
+			subs = append(subs, old)
+		}
+	}
+	s.subs = subs
+
+	result := make([]byte, 0, len(s.Data)+delta)
+	result = append(result, s.Data[:start]...)
+	result = append(result, data...)
+	s.Data = append(result, s.Data[end:]...)
+}
+
+// OriginalPosition returns the position in the golden file of offset in the document before variables were replaced.
+func (s *Source) OriginalPosition(orig int) Position {
+	if orig > len(s.original) {
+		orig = len(s.original)
+	}
+
+	line := bytes.Count(s.original[:orig], []byte("\n"))
+	col := orig - bytes.LastIndexByte(s.original[:orig], '\n')
+	fileLine := line + 1
+	if line < len(s.lines) {
+		fileLine = s.lines[line]
+	}
+	return Position{
+		File: s.Name,
+		Line: fileLine,
+		Col:  col,
+	}
+}
+
+// Locate returns the position of the expected value at path, where path is a path reported by Walk.  If path cannot
+// be fully resolved, the position of the closest ancestor is returned.  Returns false if parser does not implement
+// Locator or cannot locate values in the document.
+func (s *Source) Locate(path string, parser Parser) (Position, bool) {
+	locator, ok := parser.(Locator)
+	if !ok || !locator.Locates(s.Data) {
+		return Position{}, false
+	}
+	node := Node{
+		Type:  Object,
+		Value: s.Data,
+	}
+	offset := 0
+	for _, seg := range splitPath(path) {
+		next, ok := childNode(node, seg, parser)
+		if !ok {
+			break
+		}
+		node = next
+		offset += next.Offset
+	}
+	if node.Type == String && offset > 0 {
+		// Point to the opening quote
+		offset--
+	}
+	return s.Position(skipSpace(s.Data, offset)), true
+}
+
+// splitPath splits a path like ".connections.id=0002[2].payload|json.name" into segments ".connections", ".id=0002",
+// "[2", ".payload", "|json", ".name".
+func splitPath(path string) []string {
+	var segs []string
+	start := -1
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '.', '[', '|':
+			if start >= 0 {
+				segs = append(segs, strings.TrimSuffix(path[start:i], "]"))
+			}
+			start = i
+		}
+	}
+	if start >= 0 {
+		segs = append(segs, strings.TrimSuffix(path[start:], "]"))
+	}
+	return segs
+}
+
+// childNode returns the child of node identified by a path segment.
+func childNode(node Node, seg string, parser Parser) (Node, bool) {
+	switch node.Type {
+	case Object:
+		if seg[0] == '|' {
+			// The embedded document is described by the object itself
+			return Node{
+				Type:  node.Type,
+				Value: node.Value,
+			}, true
+		}
+		if seg[0] != '.' {
+			return Node{}, false
+		}
+		fields := parser.GetFields(node.Value)
+		child, ok := fields[seg[1:]]
+		if !ok {
+			// Values of keys that are not in the golden value are matched with KeyEachKey or KeyValues
+			if eachKeys, err := createEachKeyExpectations(fields); err == nil {
+				if exps := eachKeys.find(seg[1:]); len(exps) > 0 {
+					return exps[0], true
+				}
+			}
+			child, ok = fields[KeyValues]
+		}
+		return child, ok
+	case Array:
+		elements := parser.GetArray(node.Value)
+		if seg[0] == '[' {
+			index, err := strconv.Atoi(seg[1:])
+			if err != nil {
+				return Node{}, false
+			}
+			for _, e := range elements {
+				if e.Type != Object {
+					continue
+				}
+				if i, ok := parser.GetFields(e.Value)[KeyIndex]; ok && string(i.Value) == seg[1:] {
+					return e, true
+				}
+			}
+			for _, e := range elements {
+				if e.Type != Object {
+					continue
+				}
+				if fields := parser.GetFields(e.Value); len(fields) == 1 {
+					if each, ok := fields[KeyEach]; ok {
+						// Offset of the expectation in the array
+						each.Offset += e.Offset
+						return each, true
+					}
+				}
+			}
+			if index < len(elements) {
+				return elements[index], true
+			}
+			return Node{}, false
+		}
+		for _, e := range elements {
+			if e.Type != Object {
+				continue
+			}
+			if id, ok := parser.GetFields(e.Value)[KeyID]; ok && string(id.Value) == seg[1:] {
+				return e, true
+			}
+		}
+	}
+	return Node{}, false
+}
+
+func skipSpace(data []byte, offset int) int {
+	for offset < len(data) && (data[offset] == ' ' || data[offset] == '\t' || data[offset] == '\n' || data[offset] == '\r') {
+		offset++
+	}
+	return offset
+}
@@ -0,0 +1,92 @@
+package matcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSource_Locate(t *testing.T) {
+	data := []byte(`{
+  "name": "${{FIRST}} ${{LAST}}", "age": "${{AGE}}",
+  "connections": [
+    {
+      "_gst_index": 1,
+      "id": "0002"
+    }
+  ],
+  "teams": [
+    {"_gst_id": "id=t1", "name": "IMF"}
+  ],
+  "payload": {"_gst_embedded": "json", "orderId": "1234"},
+  "users": {"_gst_values": {"name": "{{Not(BeEmpty())}}"}},
+  "teamsById": {"_gst_each_key(^t\\d+$)": {"name": "{{Not(BeEmpty())}}"}}
+}`)
+	source, err := NewSource("golden.json", data, nil, map[string]string{"FIRST": "Ethan", "LAST": "Hunt", "AGE": "36"}, NewGenerator(1, time.Now()))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+
+	cases := map[string]string{
+		"":                      "golden.json:1:1",
+		".name":                 "golden.json:2:11",
+		".age":                  "golden.json:2:42",
+		".connections":          "golden.json:3:18",
+		".connections[1].id":    "golden.json:6:13",
+		".teams.id=t1.name":     "golden.json:10:34",
+		".teams.id=t1.missing":  "golden.json:10:5",
+		".payload|json.orderId": "golden.json:12:51",
+		".users.u1.name":        "golden.json:13:37",
+		".teamsById.t1.name":    "golden.json:14:52",
+	}
+	for path, expected := range cases {
+		pos, ok := source.Locate(path, JSONParserInstance)
+		if !ok {
+			t.Fatalf("Locate(%s) should succeed", path)
+		}
+		if pos.String() != expected {
+			t.Fatalf("Locate(%s) should be %s but was %s", path, expected, pos.String())
+		}
+	}
+
+	// YAML documents are only located if they are also JSON
+	if pos, ok := source.Locate(".connections[1].id", YAMLParserInstance); !ok || pos.String() != "golden.json:6:13" {
+		t.Fatalf("Locate() with YAML parser should be golden.json:6:13 but was %s", pos.String())
+	}
+	yamlSource, err := NewSource("golden.yaml", []byte("name: Ethan\n"), nil, nil, NewGenerator(1, time.Now()))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if _, ok := yamlSource.Locate(".name", YAMLParserInstance); ok {
+		t.Fatalf("Locate() should fail for YAML documents")
+	}
+}
+
+func TestSource_Position_Lines(t *testing.T) {
+	data := []byte("{\n  \"foo\": \"${{FOO}}\"\n}\n")
+	source, err := NewSource("", data, []int{3, 5, 6}, map[string]string{"FOO": "a much longer value"}, NewGenerator(1, time.Now()))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+
+	pos := source.Position(len(source.Data) - 2)
+	if pos.String() != "6:1" {
+		t.Fatalf("Position() should be 6:1 but was %s", pos.String())
+	}
+}
+
+func TestNewSource_GeneratorVars(t *testing.T) {
+	data := []byte(`{"id": "${{@uuid}}", "createdAt": "${{@now+1x}}"}`)
+	if _, err := NewSource("", data, nil, nil, NewGenerator(1, time.Now())); err == nil {
+		t.Fatalf("err should not be nil")
+	}
+
+	// vars take precedence over the generator, e.g. placeholders for invalid generator variables
+	source, err := NewSource("", data, nil, map[string]string{"@now+1x": "placeholder"}, NewGenerator(1, time.Now()))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	fields := JSONParserInstance.GetFields(source.Data)
+	if string(fields["createdAt"].Value) != "placeholder" || len(fields["id"].Value) != 36 {
+		t.Fatalf("variables replaced incorrectly, %s", string(source.Data))
+	}
+}
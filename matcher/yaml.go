@@ -1,3 +1,97 @@
 package matcher
 
-// TODO yaml parser is not implemented yet
+import (
+	"encoding/json"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	// YAMLParserInstance is a singleton.
+	YAMLParserInstance = &YAMLParser{}
+)
+
+// YAMLParser is parser for YAML.  Documents are converted to JSON, then parsed with JSONParser.  Values returned by
+// GetFields and GetArray are JSON, which is valid YAML.
+//
+// Note that in YAML, functions must be quoted, e.g. name: "{{Not(BeEmpty())}}".
+type YAMLParser struct {
+}
+
+// ValidateObject implements Parser.
+func (p *YAMLParser) ValidateObject(data []byte) error {
+	bs, err := yamlToJSON(data)
+	if err != nil {
+		return err
+	}
+	return JSONParserInstance.ValidateObject(bs)
+}
+
+// GetFields implements Parser.
+func (p *YAMLParser) GetFields(data []byte) map[string]Node {
+	bs, err := yamlToJSON(data)
+	if err != nil {
+		return map[string]Node{}
+	}
+	return JSONParserInstance.GetFields(bs)
+}
+
+// GetArray implements Parser.
+func (p *YAMLParser) GetArray(data []byte) []Node {
+	bs, err := yamlToJSON(data)
+	if err != nil {
+		return nil
+	}
+	return JSONParserInstance.GetArray(bs)
+}
+
+// Delete implements Parser.
+func (p *YAMLParser) Delete(data []byte, key string) []byte {
+	bs, err := yamlToJSON(data)
+	if err != nil {
+		return data
+	}
+	return JSONParserInstance.Delete(bs, key)
+}
+
+// Unescape implements Unescaper.
+func (p *YAMLParser) Unescape(value []byte) ([]byte, error) {
+	return JSONParserInstance.Unescape(value)
+}
+
+// Locates implements Locator.  Only YAML documents that are also JSON are located, others are converted.
+func (p *YAMLParser) Locates(data []byte) bool {
+	return json.Valid(data)
+}
+
+// yamlToJSON converts a YAML document to JSON.  JSON documents are returned as is.
+func yamlToJSON(data []byte) ([]byte, error) {
+	if json.Valid(data) {
+		return data, nil
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonCompatible(v))
+}
+
+// jsonCompatible converts maps decoded by yaml.v2 (map[interface{}]interface{}) to map[string]interface{}.
+func jsonCompatible(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, e := range t {
+			m[fmt.Sprintf("%v", k)] = jsonCompatible(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = jsonCompatible(e)
+		}
+		return a
+	}
+	return v
+}
@@ -0,0 +1,57 @@
+package matcher
+
+import (
+	"testing"
+)
+
+func TestYAMLParser_Walk(t *testing.T) {
+	exp := Node{
+		Type: Object,
+		Value: []byte(`
+name: Ethan Hunt
+age: 36
+interests: [climbing, jogging]
+connections:
+  - _gst_id: id=0002
+    name: Julia Meade
+updatedAt: "{{BeTimestamp(2018-10-05T12:13:14.000Z, 5000)}}"
+`),
+	}
+	act := Node{
+		Type: Object,
+		Value: []byte(`
+name: Ethan Hunt
+age: 36
+interests:
+  - jogging
+  - climbing
+connections:
+  - id: "0002"
+    name: Julia Meade
+updatedAt: 2018-10-05T12:13:14.123Z
+`),
+	}
+	matcher, matched, err := Walk("", exp, act, YAMLParserInstance)
+	if matcher != SuccessMatcherInstance {
+		t.Fatalf("matcher should be SuccessMatcherInstance but was %+v, matched = %t, err = %+v", matcher, matched, err)
+	}
+
+	// JSON is valid YAML
+	act.Value = []byte(`{"name": "Benji Dunn", "age": 36, "interests": [], "connections": [], "updatedAt": "2018-10-05T12:13:14.123Z"}`)
+	matcher, matched, _ = Walk("", exp, act, YAMLParserInstance)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if fm := matcher.(*FailureMatcher); fm.Path != ".connections.id=0002" {
+		t.Fatalf("path incorrect, %s", fm.Path)
+	}
+}
+
+func TestYAMLParser_Invalid(t *testing.T) {
+	if err := YAMLParserInstance.ValidateObject([]byte("a: [")); err == nil {
+		t.Fatalf("err should not be nil")
+	}
+	if err := YAMLParserInstance.ValidateObject([]byte("- a")); err == nil {
+		t.Fatalf("err should not be nil for arrays")
+	}
+}
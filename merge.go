@@ -0,0 +1,288 @@
+package gosert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+// splice replaces source.Data[start:end] with data.
+type splice struct {
+	start, end int
+	data       []byte
+	// parent is set if the object at start should be merged with parent instead
+	parent *matcher.Node
+}
+
+// extend deep-merges the parent object into the object in source.  Fields of source override fields of parent, objects
+// are merged and other values, including arrays, are replaced.  Inherited fields listed in matcher.KeyDelete are
+// deleted.
+//
+// parent is parsed with parentParser and source with parser.  If they are the same and locate values in both (see
+// matcher.Locator), positions of fields defined in source are kept, and inherited fields are located at the opening brace of their
+// object.  Otherwise, source is replaced with the merged value as JSON, and all fields are located at its start.
+func extend(source *matcher.Source, parent []byte, parentParser, parser matcher.Parser) error {
+	child := matcher.DocumentNode(source.Data)
+	parentNode := matcher.DocumentNode(parent)
+	if child.Type != matcher.Object || parentNode.Type != matcher.Object {
+		return fmt.Errorf("only objects can be extended")
+	}
+	locator, ok := parser.(matcher.Locator)
+	if !ok || parentParser != parser || !locator.Locates(source.Data) || !locator.Locates(parent) {
+		parentValue, err := decodeJSON(parentNode, parentParser)
+		if err != nil {
+			return err
+		}
+		childValue, err := decodeJSON(child, parser)
+		if err != nil {
+			return err
+		}
+		merged, err := mergeValues(parentValue, childValue)
+		if err != nil {
+			return err
+		}
+		bs, err := json.Marshal(merged)
+		if err != nil {
+			return err
+		}
+		source.Splice(0, len(source.Data), bs)
+		return nil
+	}
+	// DocumentNode trims leading space
+	offset := len(source.Data) - len(bytes.TrimLeft(source.Data, " \t\r\n"))
+	return mergeObject(source, offset, len(child.Value), parentNode, parser)
+}
+
+// mergeObject merges parent into the object at source.Data[offset:offset+length].
+func mergeObject(source *matcher.Source, offset, length int, parent matcher.Node, parser matcher.Parser) error {
+	value := source.Data[offset : offset+length]
+	fields := parser.GetFields(value)
+	parentFields := parser.GetFields(parent.Value)
+
+	var splices []splice
+	deleted := map[string]bool{}
+	if marker, ok := fields[matcher.KeyDelete]; ok {
+		keys, err := deletedKeys(marker, parser)
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			deleted[k] = true
+		}
+		start, end := fieldSpan(value, matcher.KeyDelete, marker)
+		splices = append(splices, splice{
+			start: offset + start,
+			end:   offset + end,
+		})
+		delete(fields, matcher.KeyDelete)
+	}
+	for k, f := range fields {
+		p, ok := parentFields[k]
+		if !ok || f.Type != matcher.Object || p.Type != matcher.Object {
+			continue
+		}
+		parentValue := p
+		splices = append(splices, splice{
+			start:  offset + f.Offset,
+			end:    offset + f.Offset + len(f.Value),
+			parent: &parentValue,
+		})
+	}
+
+	// Splice from the end, so that offsets of earlier splices stay valid
+	sort.Slice(splices, func(i, j int) bool {
+		return splices[i].start > splices[j].start
+	})
+	for _, s := range splices {
+		if s.parent != nil {
+			if err := mergeObject(source, s.start, s.end-s.start, *s.parent, parser); err != nil {
+				return err
+			}
+		} else {
+			source.Splice(s.start, s.end, s.data)
+		}
+	}
+
+	// Inherited fields keep the order of parent
+	var inherited []string
+	for k := range parentFields {
+		if _, ok := fields[k]; !ok && !deleted[k] {
+			inherited = append(inherited, k)
+		}
+	}
+	sort.Slice(inherited, func(i, j int) bool {
+		return parentFields[inherited[i]].Offset < parentFields[inherited[j]].Offset
+	})
+	if len(inherited) == 0 {
+		return nil
+	}
+	var kvs []string
+	for _, k := range inherited {
+		// Keys returned by GetFields are unescaped
+		key, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+		kvs = append(kvs, fmt.Sprintf(`%s:%s`, key, parentFields[k].Raw()))
+	}
+	data := strings.Join(kvs, ",")
+	if len(fields) > 0 {
+		data += ","
+	}
+	// Insert after the opening brace
+	source.Splice(offset+1, offset+1, []byte(data))
+	return nil
+}
+
+// findDeleteMarker returns the path of the first matcher.KeyDelete field in node, in the form reported by Walk.
+func findDeleteMarker(node matcher.Node, path string, parser matcher.Parser) (string, bool) {
+	switch node.Type {
+	case matcher.Object:
+		fields := parser.GetFields(node.Value)
+		if _, ok := fields[matcher.KeyDelete]; ok {
+			return path + "." + matcher.KeyDelete, true
+		}
+		for _, k := range sortedFieldKeys(fields) {
+			if found, ok := findDeleteMarker(fields[k], path+"."+k, parser); ok {
+				return found, true
+			}
+		}
+	case matcher.Array:
+		for i, e := range parser.GetArray(node.Value) {
+			if found, ok := findDeleteMarker(e, fmt.Sprintf("%s[%d]", path, i), parser); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
+
+func sortedFieldKeys(fields map[string]matcher.Node) []string {
+	var keys []string
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// deletedKeys returns the keys in the KeyDelete marker.
+func deletedKeys(marker matcher.Node, parser matcher.Parser) ([]string, error) {
+	if marker.Type != matcher.Array {
+		return nil, fmt.Errorf("'%s' field must be an array of keys, was %s", matcher.KeyDelete, string(marker.Raw()))
+	}
+	var keys []string
+	for _, node := range parser.GetArray(marker.Value) {
+		if node.Type != matcher.String {
+			return nil, fmt.Errorf("'%s' field must be an array of keys, was %s", matcher.KeyDelete, string(marker.Raw()))
+		}
+		keys = append(keys, string(node.Value))
+	}
+	return keys, nil
+}
+
+// fieldSpan returns the span of the field key with value in the object data, including the comma that separates it
+// from other fields.
+func fieldSpan(data []byte, key string, value matcher.Node) (int, int) {
+	valueStart := value.Offset
+	end := valueStart + len(value.Value)
+	if value.Type == matcher.String {
+		end++
+	}
+	start := bytes.LastIndex(data[:valueStart], []byte(`"`+key+`"`))
+	if next := skipSpace(data, end); next < len(data) && data[next] == ',' {
+		return start, next + 1
+	}
+	prev := len(bytes.TrimRight(data[:start], " \t\r\n"))
+	if prev > 0 && data[prev-1] == ',' {
+		return prev - 1, end
+	}
+	return start, end
+}
+
+func skipSpace(data []byte, offset int) int {
+	for offset < len(data) && strings.IndexByte(" \t\r\n", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// decodeJSON decodes node like matcher.Decode, except that strings are unescaped and numbers are kept as json.Number,
+// so that they are written back unchanged by json.Marshal.
+func decodeJSON(node matcher.Node, parser matcher.Parser) (interface{}, error) {
+	switch node.Type {
+	case matcher.String:
+		if u, ok := parser.(matcher.Unescaper); ok {
+			str, err := u.Unescape(node.Value)
+			if err != nil {
+				return nil, err
+			}
+			return string(str), nil
+		}
+		return string(node.Value), nil
+	case matcher.Number:
+		return json.Number(node.Value), nil
+	case matcher.Object:
+		m := map[string]interface{}{}
+		for k, f := range parser.GetFields(node.Value) {
+			v, err := decodeJSON(f, parser)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v
+		}
+		return m, nil
+	case matcher.Array:
+		a := []interface{}{}
+		for _, e := range parser.GetArray(node.Value) {
+			v, err := decodeJSON(e, parser)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	}
+	return matcher.Decode(node, parser), nil
+}
+
+// mergeValues merges decoded values like extend.
+func mergeValues(parent, child interface{}) (interface{}, error) {
+	p, ok := parent.(map[string]interface{})
+	c, ok2 := child.(map[string]interface{})
+	if !ok || !ok2 {
+		return child, nil
+	}
+	result := map[string]interface{}{}
+	for k, v := range p {
+		result[k] = v
+	}
+	if marker, ok := c[matcher.KeyDelete]; ok {
+		keys, ok := marker.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("'%s' field must be an array of keys", matcher.KeyDelete)
+		}
+		for _, k := range keys {
+			s, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("'%s' field must be an array of keys", matcher.KeyDelete)
+			}
+			delete(result, s)
+		}
+	}
+	for k, v := range c {
+		if k == matcher.KeyDelete {
+			continue
+		}
+		merged, err := mergeValues(result[k], v)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = merged
+	}
+	return result, nil
+}
@@ -0,0 +1,136 @@
+package gosert
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mina-akimi/gosert/v2/matcher"
+	"github.com/onsi/gomega/types"
+)
+
+// EnvUpdate is the environment variable that turns on golden file updates in AssertFile, e.g. GOSERT_UPDATE=1.
+const EnvUpdate = "GOSERT_UPDATE"
+
+// Option configures a Matcher (see New), Assert and AssertFile.
+type Option func(*options)
+
+type options struct {
+	vars          map[string]string
+	parser        matcher.Parser
+	strictObjects bool
+	tolerance     float64
+	clock         func() time.Time
+	functions     map[string]matcher.Function
+	matchers      map[string]types.GomegaMatcher
+	update        bool
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		parser:    matcher.JSONParserInstance,
+		tolerance: matcher.DefaultNumberTolerance,
+		clock:     time.Now,
+		update:    os.Getenv(EnvUpdate) != "",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if len(o.matchers) > 0 {
+		functions := map[string]matcher.Function{
+			"Use": matcher.UseFunction(o.matchers),
+		}
+		for name, f := range o.functions {
+			functions[name] = f
+		}
+		o.functions = functions
+	}
+	return o
+}
+
+// WithVars sets the variables to replace in the golden value.
+func WithVars(vars map[string]string) Option {
+	return func(o *options) {
+		o.vars = vars
+	}
+}
+
+// WithParser sets the parser.  The default is matcher.JSONParserInstance.
+func WithParser(parser matcher.Parser) Option {
+	return func(o *options) {
+		o.parser = parser
+	}
+}
+
+// WithStrictObjects sets whether actual objects fail to match if they have fields that are not in the golden value.
+// The default is false.
+func WithStrictObjects(strict bool) Option {
+	return func(o *options) {
+		o.strictObjects = strict
+	}
+}
+
+// WithNumberTolerance sets the tolerance used when comparing an actual number with an expected number.  The default
+// is matcher.DefaultNumberTolerance.
+func WithNumberTolerance(tolerance float64) Option {
+	return func(o *options) {
+		o.tolerance = tolerance
+	}
+}
+
+// WithClock sets the clock used for generator variables such as ${{@now}}.  The default is time.Now.
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithFunctions adds custom functions that can be used in the golden value, e.g. {{MyFunction(a, b)}}.
+func WithFunctions(functions map[string]matcher.Function) Option {
+	return func(o *options) {
+		if o.functions == nil {
+			o.functions = map[string]matcher.Function{}
+		}
+		for name, f := range functions {
+			o.functions[name] = f
+		}
+	}
+}
+
+// WithMatcher adds a gomega matcher that can be used in the golden value by name, e.g. {{Use(name)}}.  The matcher
+// is matched against the decoded actual value (see matcher.Decode).
+func WithMatcher(name string, m types.GomegaMatcher) Option {
+	return func(o *options) {
+		if o.matchers == nil {
+			o.matchers = map[string]types.GomegaMatcher{}
+		}
+		o.matchers[name] = m
+	}
+}
+
+// WithUpdate sets whether AssertFile overwrites the golden file with the actual value on mismatch.  The default is
+// true if the environment variable GOSERT_UPDATE is set.
+func WithUpdate(update bool) Option {
+	return func(o *options) {
+		o.update = update
+	}
+}
+
+// functionsFor returns the functions for the golden document name parsed with parser.  MatchSchema resolves schema
+// paths against the directory of name, like includes, unless it is one of functions.
+func functionsFor(name string, parser matcher.Parser, functions map[string]matcher.Function) map[string]matcher.Function {
+	if _, ok := functions["MatchSchema"]; ok {
+		return functions
+	}
+	dir := "."
+	if name != "" {
+		dir = filepath.Dir(name)
+	}
+	all := map[string]matcher.Function{
+		"MatchSchema": matcher.MatchSchemaFunction(dir, parser),
+	}
+	for k, f := range functions {
+		all[k] = f
+	}
+	return all
+}
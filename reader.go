@@ -8,7 +8,9 @@ import (
 	"io/ioutil"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/mina-akimi/gosert/v2/matcher"
 )
@@ -17,6 +19,8 @@ var (
 	patternComment   = regexp.MustCompile(`^\s*#[^#]`)
 	patternHeader    = regexp.MustCompile(`^\s*###`)
 	patternHeaderKey = regexp.MustCompile(`key=(?P<key>\w+)`)
+	// An attribute in a header, e.g. extends=base_error
+	patternHeaderAttribute = regexp.MustCompile(`^(\w+)=(\S+)$`)
 )
 
 // Read reads a file with variables replaced.
@@ -56,16 +60,32 @@ func MustRead(path string, vars map[string]string) []byte {
 //       "baz": "{{Not(BeEmpty())}}",
 //       "quux": "{{BeTimestamp(${{NOW}}, 5000)}}"
 //     }
+//
+// Headers can have attributes, e.g. "### key=my_fixture, format=yaml".  See AttrExtends, AttrFormat, AttrParser and
+// AttrVars.
+//
+// Generator variables such as ${{@uuid}} are evaluated once per reader, so a fixture and a matcher referencing the
+// same expression see the same value.  Use Seed for reproducible values.
 type MultipartReader struct {
-	raw    []byte
-	parts  map[string][]byte
-	parser matcher.Parser
+	raw     []byte
+	parts   map[string][]byte
+	sources map[string]*matcher.Source
+	parser  matcher.Parser
+	// parser of each section
+	parsers map[string]matcher.Parser
+	// sections in file order
+	sections []*section
+	// path is the file the reader was created from, if any
+	path string
+	vars map[string]string
+	gen  *matcher.Generator
 }
 
 // NewMultipartReader returns a new reader.
 func NewMultipartReader(data []byte, vars map[string]string, parser matcher.Parser) (*MultipartReader, error) {
 	reader := bytes.NewReader(data)
-	return newMultipartReader(reader, vars, parser)
+	gen := newGenerator()
+	return newMultipartReader(reader, "", vars, parser, gen, newIncluder(vars, gen))
 }
 
 // NewMultipartReader returns a new reader.
@@ -76,7 +96,8 @@ func NewMultipartReaderFromFile(path string, vars map[string]string, parser matc
 	}
 	defer file.Close()
 
-	return newMultipartReader(file, vars, parser)
+	gen := newGenerator()
+	return newMultipartReader(file, path, vars, parser, gen, newIncluder(vars, gen))
 }
 
 // MustReader panics if an error occurs.
@@ -87,62 +108,56 @@ func MustReader(r *MultipartReader, err error) *MultipartReader {
 	return r
 }
 
-func newMultipartReader(reader io.Reader, vars map[string]string, parser matcher.Parser) (*MultipartReader, error) {
-	scanner := bufio.NewScanner(reader)
-	var key string
-	var object []byte
-	var raw []byte
-	parts := map[string][]byte{}
-	for scanner.Scan() {
-		raw = append(raw, scanner.Bytes()...)
-		raw = append(raw, []byte(fmt.Sprintln())...)
-		s := strings.TrimSpace(scanner.Text())
-		if s == "" || patternComment.MatchString(s) {
-			continue
-		}
-		if patternHeader.MatchString(s) {
-			// Add to parts
-			if len(object) > 0 {
-				if key == "" {
-					return nil, fmt.Errorf("multipart file cannot have header with empty body.  See Gosert doc.")
-				}
-				replaced, err := matcher.Replace(object, vars)
-				if err != nil {
-					return nil, err
-				}
-				parts[key] = replaced
-			}
+func newGenerator() *matcher.Generator {
+	now := time.Now()
+	return matcher.NewGenerator(now.UnixNano(), now)
+}
 
-			// New key and clear lines
-			m := patternHeaderKey.FindStringSubmatch(s)
-			if len(m) > 0 {
-				key = m[1]
-			}
-			object = nil
-		} else {
-			if key == "" {
-				return nil, fmt.Errorf("section multipart file must have key.  See Gosert doc.")
-			}
-			object = append(object, scanner.Bytes()...)
-			object = append(object, []byte(fmt.Sprintln())...)
-		}
-	}
-	if scanner.Err() != nil {
-		return nil, scanner.Err()
+// newMultipartReader returns a new reader.  Includes are expanded by inc, if not nil.
+func newMultipartReader(reader io.Reader, path string, vars map[string]string, parser matcher.Parser, gen *matcher.Generator, inc *includer) (*MultipartReader, error) {
+	sections, raw, err := readSections(reader)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(object) > 0 {
-		bs, err := matcher.Replace(object, vars)
+	b := &sectionBuilder{
+		sections: map[string]*section{},
+		sources:  map[string]*matcher.Source{},
+		parsers:  map[string]matcher.Parser{},
+		path:     path,
+		vars:     vars,
+		parser:   parser,
+		gen:      gen,
+		inc:      inc,
+	}
+	for _, s := range sections {
+		b.sections[s.key] = s
+	}
+	parts := map[string][]byte{}
+	var effective []*section
+	for _, s := range sections {
+		if b.sections[s.key] != s {
+			// Replaced by a later section with the same key
+			continue
+		}
+		source, err := b.build(s.key, nil)
 		if err != nil {
 			return nil, err
 		}
-		parts[key] = bs
+		parts[s.key] = source.Data
+		effective = append(effective, s)
 	}
 
 	return &MultipartReader{
-		raw:    raw,
-		parts:  parts,
-		parser: parser,
+		raw:      raw,
+		parts:    parts,
+		sources:  b.sources,
+		parser:   parser,
+		parsers:  b.parsers,
+		sections: effective,
+		path:     path,
+		vars:     vars,
+		gen:      gen,
 	}, nil
 }
 
@@ -153,12 +168,62 @@ func (r *MultipartReader) GetData(key string) []byte {
 
 // GetData returns *Matcher with variables substituted.
 func (r *MultipartReader) GetMatcher(key string) (*Matcher, error) {
-	if bs, ok := r.parts[key]; ok {
-		return NewMatcher(bs, nil, r.parser)
+	if source, ok := r.sources[key]; ok {
+		return newMatcher(source, newOptions([]Option{WithParser(r.parsers[key])})), nil
 	}
 	return nil, fmt.Errorf("no such key '%s' in file.  See Gosert doc.", key)
 }
 
+// Keys returns the keys of the sections in file order.
+func (r *MultipartReader) Keys() []string {
+	var keys []string
+	for _, s := range r.sections {
+		keys = append(keys, s.key)
+	}
+	return keys
+}
+
+// Section is a section of a multipart file.
+type Section struct {
+	Key string
+	// Description is the part of the header that is not attributes, e.g. "my fixture"
+	Description string
+	// Attributes are the attributes in the header other than key, e.g. {"format": "yaml"}
+	Attributes map[string]string
+	// StartLine is the line of the header and EndLine the last line of the body (1-based)
+	StartLine, EndLine int
+	// Raw is the body as it is in the file
+	Raw []byte
+	// Data is the body with variables substituted, includes expanded and merged with the section it extends, see
+	// GetData
+	Data []byte
+}
+
+// Sections returns the sections in file order.  If a key is defined more than once, the last section is used.
+//
+//	for _, s := range r.Sections() {
+//		t.Run(s.Key, func(t *testing.T) { ... })
+//	}
+func (r *MultipartReader) Sections() []Section {
+	var sections []Section
+	for _, s := range r.sections {
+		attrs := map[string]string{}
+		for k, v := range s.attrs {
+			attrs[k] = v
+		}
+		sections = append(sections, Section{
+			Key:         s.key,
+			Description: s.description,
+			Attributes:  attrs,
+			StartLine:   s.line,
+			EndLine:     s.lines[len(s.lines)-1],
+			Raw:         s.object,
+			Data:        r.parts[s.key],
+		})
+	}
+	return sections
+}
+
 // MustGetMatcher panics if an error occurs.
 func (r *MultipartReader) MustGetMatcher(key string) *Matcher {
 	m, err := r.GetMatcher(key)
@@ -169,11 +234,311 @@ func (r *MultipartReader) MustGetMatcher(key string) *Matcher {
 }
 
 // UpdateVars updates r's variable substitution.  New matchers must be generated to take effect.
+//
+// Values of generator variables are kept.
 func (r *MultipartReader) UpdateVars(vars map[string]string) error {
-	nr, err := newMultipartReader(bytes.NewReader(r.raw), vars, r.parser)
+	nr, err := newMultipartReader(bytes.NewReader(r.raw), r.path, vars, r.parser, r.gen, newIncluder(vars, r.gen))
 	if err != nil {
 		return err
 	}
 	r.parts = nr.parts
+	r.sources = nr.sources
+	r.parsers = nr.parsers
+	r.sections = nr.sections
+	r.vars = vars
 	return nil
 }
+
+// Seed re-evaluates generator variables with a generator seeded with seed.  New matchers must be generated to take
+// effect.  ${{@now}} is still the current time, see SeedWithClock.
+func (r *MultipartReader) Seed(seed int64) error {
+	return r.SeedWithClock(seed, time.Now)
+}
+
+// SeedWithClock is like Seed, with ${{@now}} read from clock, so that all generated values are reproducible.
+func (r *MultipartReader) SeedWithClock(seed int64, clock func() time.Time) error {
+	gen := matcher.NewGenerator(seed, clock())
+	nr, err := newMultipartReader(bytes.NewReader(r.raw), r.path, r.vars, r.parser, gen, newIncluder(r.vars, gen))
+	if err != nil {
+		return err
+	}
+	r.parts = nr.parts
+	r.sources = nr.sources
+	r.parsers = nr.parsers
+	r.sections = nr.sections
+	r.gen = gen
+	return nil
+}
+
+// Attributes of multipart headers.
+const (
+	// AttrExtends makes a section extend another section, e.g. extends=base_error
+	AttrExtends = "extends"
+	// AttrFormat is the format of a section: json or yaml.  The default is the parser of the reader.
+	AttrFormat = "format"
+	// AttrParser is the name of a parser registered with matcher.RegisterParser to parse a section
+	AttrParser = "parser"
+	// AttrVars is how undefined variables in a section are handled: strict (the default) fails, lenient leaves them
+	// as they are
+	AttrVars = "vars"
+	// AttrRole makes a section the input or the expected value of a case, see Cases.  The role is appended to the key,
+	// e.g. "### key=case1, role=input" is the section case1_input.
+	AttrRole = "role"
+)
+
+// Roles of sections in cases.
+const (
+	RoleInput    = "input"
+	RoleExpected = "expected"
+)
+
+// header is a parsed section header, e.g. "### key=error_404, extends=base_error, not found".
+type header struct {
+	key string
+	// attributes other than key
+	attrs       map[string]string
+	description string
+}
+
+// parseHeader parses a header line.  Parts separated by commas are attributes if they have the form name=value,
+// otherwise they are the description.  Parts after the start of the description are part of it too, except key=, so
+// that descriptions can contain text like "status=200".
+func parseHeader(line string) header {
+	h := header{
+		attrs: map[string]string{},
+	}
+	var desc []string
+	for _, part := range strings.Split(strings.TrimLeft(strings.TrimSpace(line), "#"), ",") {
+		part = strings.TrimSpace(part)
+		m := patternHeaderAttribute.FindStringSubmatch(part)
+		switch {
+		case m == nil || (len(desc) > 0 && m[1] != "key"):
+			if part != "" {
+				desc = append(desc, part)
+			}
+		case m[1] == "key":
+			h.key = m[2]
+		default:
+			h.attrs[m[1]] = m[2]
+		}
+	}
+	if h.key == "" {
+		if m := patternHeaderKey.FindStringSubmatch(line); len(m) > 0 {
+			h.key = m[1]
+		}
+	}
+	h.description = strings.Join(desc, ", ")
+	if role := h.attrs[AttrRole]; h.key != "" && (role == RoleInput || role == RoleExpected) {
+		h.key += "_" + role
+	}
+	return h
+}
+
+// check returns an error if h has unknown attributes or attributes with invalid values.
+func (h header) check() error {
+	for _, name := range sortedAttrs(h.attrs) {
+		value := h.attrs[name]
+		switch name {
+		case AttrExtends:
+		case AttrFormat:
+			if value != "json" && value != "yaml" {
+				return fmt.Errorf("section '%s' has invalid %s=%s, must be json or yaml", h.key, name, value)
+			}
+		case AttrParser:
+			if _, ok := matcher.LookupParser(value); !ok {
+				return fmt.Errorf("section '%s' has unknown %s=%s, see matcher.RegisterParser", h.key, name, value)
+			}
+		case AttrVars:
+			if value != "strict" && value != "lenient" {
+				return fmt.Errorf("section '%s' has invalid %s=%s, must be strict or lenient", h.key, name, value)
+			}
+		case AttrRole:
+			if value != RoleInput && value != RoleExpected {
+				return fmt.Errorf("section '%s' has invalid %s=%s, must be %s or %s", h.key, name, value, RoleInput, RoleExpected)
+			}
+		default:
+			return fmt.Errorf("section '%s' has unknown attribute %s.  See Gosert doc.", h.key, name)
+		}
+	}
+	if _, ok := h.attrs[AttrFormat]; ok {
+		if _, ok := h.attrs[AttrParser]; ok {
+			return fmt.Errorf("section '%s' cannot have both %s= and %s=", h.key, AttrFormat, AttrParser)
+		}
+	}
+	return nil
+}
+
+// parser returns the parser of the section, or parser if the header has no format= or parser=.
+func (h header) parser(parser matcher.Parser) matcher.Parser {
+	if name, ok := h.attrs[AttrFormat]; ok {
+		parser, _ = matcher.LookupParser(name)
+	}
+	if name, ok := h.attrs[AttrParser]; ok {
+		parser, _ = matcher.LookupParser(name)
+	}
+	return parser
+}
+
+// vars returns the variables to replace in object.  With vars=lenient, undefined variables are replaced with
+// themselves.
+func (h header) vars(vars map[string]string, object []byte) map[string]string {
+	if h.attrs[AttrVars] != "lenient" {
+		return vars
+	}
+	result := map[string]string{}
+	for k, v := range vars {
+		result[k] = v
+	}
+	for _, variable := range matcher.Variables(object) {
+		if _, ok := result[variable.Name]; !ok && !strings.HasPrefix(variable.Name, "@") {
+			result[variable.Name] = "${{" + variable.Name + "}}"
+		}
+	}
+	return result
+}
+
+func sortedAttrs(attrs map[string]string) []string {
+	var names []string
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// section is a section in a multipart file.
+type section struct {
+	header
+	// line of the header
+	line   int
+	object []byte
+	// line numbers of lines in object
+	lines []int
+}
+
+// readSections splits a multipart file into sections with a body, in order.  It also returns the content of the file.
+func readSections(reader io.Reader) ([]*section, []byte, error) {
+	scanned, raw, err := scanSections(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sections []*section
+	var prev *section
+	for _, s := range scanned {
+		if s.line > 0 {
+			if err := s.check(); err != nil {
+				return nil, nil, err
+			}
+			if s.key == "" && prev != nil {
+				// Keep the key of the previous section
+				s.key = prev.key
+			}
+			prev = s
+		}
+		if len(s.object) == 0 {
+			continue
+		}
+		if s.key == "" {
+			return nil, nil, fmt.Errorf("section multipart file must have key.  See Gosert doc.")
+		}
+		sections = append(sections, s)
+	}
+	return sections, raw, nil
+}
+
+// scanSections splits a multipart file into sections at headers, in order, without checking them.  Content before the
+// first header is a section with line 0.  It also returns the content of the file.
+func scanSections(reader io.Reader) ([]*section, []byte, error) {
+	scanner := bufio.NewScanner(reader)
+	var sections []*section
+	var cur *section
+	var raw []byte
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw = append(raw, scanner.Bytes()...)
+		raw = append(raw, []byte(fmt.Sprintln())...)
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || patternComment.MatchString(s) {
+			continue
+		}
+		if patternHeader.MatchString(s) {
+			cur = &section{
+				header: parseHeader(s),
+				line:   lineNo,
+			}
+			sections = append(sections, cur)
+			continue
+		}
+		if cur == nil {
+			cur = &section{
+				header: header{
+					attrs: map[string]string{},
+				},
+			}
+			sections = append(sections, cur)
+		}
+		cur.object = append(cur.object, scanner.Bytes()...)
+		cur.object = append(cur.object, []byte(fmt.Sprintln())...)
+		cur.lines = append(cur.lines, lineNo)
+	}
+	if scanner.Err() != nil {
+		return nil, nil, scanner.Err()
+	}
+	return sections, raw, nil
+}
+
+// sectionBuilder builds the sources of sections, with sections they extend built first.
+type sectionBuilder struct {
+	// last section of each key
+	sections map[string]*section
+	sources  map[string]*matcher.Source
+	parsers  map[string]matcher.Parser
+	path     string
+	vars     map[string]string
+	parser   matcher.Parser
+	gen      *matcher.Generator
+	inc      *includer
+}
+
+// build returns the source of the section key.  stack is the sections that extend key, to detect cycles.
+func (b *sectionBuilder) build(key string, stack []string) (*matcher.Source, error) {
+	if source, ok := b.sources[key]; ok {
+		return source, nil
+	}
+	s := b.sections[key]
+	parser := s.parser(b.parser)
+	source, err := newSource(b.path, sourceID(b.path, key), s.object, s.lines, s.vars(b.vars, s.object), b.gen, b.inc)
+	if err != nil {
+		return nil, err
+	}
+	if parent, ok := s.attrs[AttrExtends]; ok {
+		stack = append(stack, key)
+		for _, k := range stack {
+			if k == parent {
+				return nil, fmt.Errorf("sections extend each other: %s -> %s", strings.Join(stack, " -> "), parent)
+			}
+		}
+		if _, ok := b.sections[parent]; !ok {
+			return nil, fmt.Errorf("section '%s' extends '%s', but there is no such key in file.  See Gosert doc.", key, parent)
+		}
+		parentSource, err := b.build(parent, stack)
+		if err != nil {
+			return nil, err
+		}
+		if err := extend(source, parentSource.Data, b.parsers[parent], parser); err != nil {
+			return nil, fmt.Errorf("section '%s' cannot extend '%s': %s", key, parent, err.Error())
+		}
+	}
+	// Markers that were not used by extend would be expected as fields
+	if path, ok := findDeleteMarker(matcher.DocumentNode(source.Data), "", parser); ok {
+		pos, ok := source.Locate(path, parser)
+		if !ok {
+			pos = source.Position(0)
+		}
+		return nil, fmt.Errorf("%s: section '%s' has '%s' in an object that does not extend an inherited object", pos.String(), key, matcher.KeyDelete)
+	}
+	b.sources[key] = source
+	b.parsers[key] = parser
+	return source, nil
+}
@@ -1,7 +1,12 @@
 package gosert
 
 import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mina-akimi/gosert/v2/matcher"
 )
@@ -91,3 +96,279 @@ func TestMultipartReader_UpdateVars(t *testing.T) {
 		t.Fatalf("matched should be true")
 	}
 }
+
+func TestMultipartReader_Generator(t *testing.T) {
+	data := []byte(`
+		### key=my_fixture, my fixture
+		{
+			"id": "${{@uuid}}",
+			"count": ${{@rand(1, 10)}},
+			"createdAt": "${{@now}}"
+		}
+
+		### key=my_matcher, my awesome matcher
+		{
+			"id": "${{@uuid}}",
+			"count": ${{@rand(1, 10)}},
+			"createdAt": "{{BeTimestamp(${{@now}}, 0)}}"
+		}
+	`)
+
+	r := MustReader(NewMultipartReader(data, nil, matcher.JSONParserInstance))
+	fixture := string(matcher.JSONParserInstance.GetFields(r.GetData("my_fixture"))["id"].Value)
+
+	matched, err := r.MustGetMatcher("my_matcher").Match(r.GetData("my_fixture"))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if !matched {
+		t.Fatalf("matched should be true")
+	}
+
+	err = r.Seed(42)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	seeded := string(matcher.JSONParserInstance.GetFields(r.GetData("my_fixture"))["id"].Value)
+	if seeded == fixture {
+		t.Fatalf("Seed() should re-evaluate generators")
+	}
+	err = r.Seed(42)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if string(matcher.JSONParserInstance.GetFields(r.GetData("my_fixture"))["id"].Value) != seeded {
+		t.Fatalf("Seed() should produce the same values for the same seed")
+	}
+
+	// With a fixed clock, ${{@now}} is reproducible too
+	clock := func() time.Time {
+		return time.Date(2018, 10, 5, 12, 13, 14, 0, time.UTC)
+	}
+	if err := r.SeedWithClock(42, clock); err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	createdAt := string(matcher.JSONParserInstance.GetFields(r.GetData("my_matcher"))["createdAt"].Value)
+	if createdAt != "{{BeTimestamp(2018-10-05T12:13:14.000Z, 0)}}" {
+		t.Fatalf("SeedWithClock() should use the clock but createdAt was %s", createdAt)
+	}
+}
+
+func TestMultipartReader_Location(t *testing.T) {
+	r := MustReader(NewMultipartReaderFromFile("test_asset/multipart1.txt", map[string]string{"ID": "1"}, matcher.JSONParserInstance))
+	m := r.MustGetMatcher("my_matcher")
+
+	matched, err := m.Match(r.GetData("my_fixture"))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+
+	failures := m.Report().Failures
+	if len(failures) != 2 {
+		t.Fatalf("report should have 2 failures but has %+v", failures)
+	}
+	if failures[0].Path != ".connections.id=0003.name" || failures[0].Location != "test_asset/multipart1.txt:23:15" {
+		t.Fatalf("failure incorrect, %+v", failures[0])
+	}
+	if failures[1].Path != ".id" || failures[1].Location != "test_asset/multipart1.txt:13:9" {
+		t.Fatalf("failure incorrect, %+v", failures[1])
+	}
+	if !strings.Contains(m.FailureMessage(nil), "at test_asset/multipart1.txt:23:15") {
+		t.Fatalf("failure message should contain location, %s", m.FailureMessage(nil))
+	}
+
+	// Matching again must give the same result
+	m.Match(r.GetData("my_fixture"))
+	if again := m.Report().Failures; len(again) != 2 || again[0] != failures[0] {
+		t.Fatalf("report should be the same but was %+v", again)
+	}
+}
+
+func TestMultipartReader_Extends(t *testing.T) {
+	r := MustReader(NewMultipartReaderFromFile("test_asset/extends1.txt", map[string]string{"USER": "0001"}, matcher.JSONParserInstance))
+	cases := []struct {
+		key      string
+		expected string
+	}{
+		{"error_404", `{"status":404,"error":{"code":"not_found","message":"{{Not(BeEmpty())}}"},"headers":["content-type"]}`},
+		{"user_not_found", `{"status":404,"error":{"code":"not_found","message":"user 0001 not found"}}`},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, r.GetData(c.key)); err != nil {
+			t.Fatalf("err should be nil but was %+v\n%s", err, r.GetData(c.key))
+		}
+		var actual, expected interface{}
+		json.Unmarshal(buf.Bytes(), &actual)
+		json.Unmarshal([]byte(c.expected), &expected)
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("data of %s incorrect\n%s", c.key, buf.String())
+		}
+	}
+
+	// Failures are located in the extending section, inherited values at the brace of their object
+	m := r.MustGetMatcher("user_not_found")
+	actual := `{"status": 404, "error": {"code": "gone", "message": "user 0002 not found"}}`
+	matched, _ := m.Match(actual)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	msg := m.FailureMessage(actual)
+	for _, s := range []string{
+		"path = .error.code, expected = not_found, actual = gone, at test_asset/extends1.txt:24:13",
+		"path = .error.message, expected = user 0001 not found, actual = user 0002 not found, at test_asset/extends1.txt:25:16",
+	} {
+		if !strings.Contains(msg, s) {
+			t.Fatalf("failure message incorrect\n%s", msg)
+		}
+	}
+}
+
+func TestMultipartReader_ExtendsErrors(t *testing.T) {
+	cases := []struct {
+		data     string
+		expected string
+	}{
+		{"### key=a, extends=b\n{}", "section 'a' extends 'b', but there is no such key in file.  See Gosert doc."},
+		{"### key=a, extends=b\n{}\n### key=b, extends=a\n{}", "sections extend each other: a -> b -> a"},
+		{"### key=a\n[]\n### key=b, extends=a\n{}", "section 'b' cannot extend 'a': only objects can be extended"},
+		{"### key=a\n{}\n### key=b, extends=a\n{\"_gst_delete\": \"x\"}", `section 'b' cannot extend 'a': '_gst_delete' field must be an array of keys, was "x"`},
+		{"### key=a\n{\"c\": 1}\n### key=b, extends=a\n{\n  \"c\": {\"_gst_delete\": [\"d\"]}\n}", "5:24: section 'b' has '_gst_delete' in an object that does not extend an inherited object"},
+		{"### key=a\n{\"_gst_delete\": [\"d\"]}", "2:17: section 'a' has '_gst_delete' in an object that does not extend an inherited object"},
+	}
+	for _, c := range cases {
+		_, err := NewMultipartReader([]byte(c.data), nil, matcher.JSONParserInstance)
+		if err == nil || err.Error() != c.expected {
+			t.Fatalf("err of %s incorrect: %+v", c.data, err)
+		}
+	}
+}
+
+func TestMultipartReader_Attributes(t *testing.T) {
+	// The fixture is strict
+	_, err := NewMultipartReaderFromFile("test_asset/attributes1.txt", map[string]string{"ID": "0001"}, matcher.JSONParserInstance)
+	if err == nil || err.Error() != "variable 'NAME' undefined in substitution" {
+		t.Fatalf("err incorrect: %+v", err)
+	}
+
+	r := MustReader(NewMultipartReaderFromFile("test_asset/attributes1.txt", map[string]string{"ID": "0001", "NAME": "Ethan"}, matcher.JSONParserInstance))
+	if string(r.GetData("fixture")) != "id: \"0001\"\nname: Ethan Hunt\ntemplate: Hello Ethan\n" {
+		t.Fatalf("data incorrect\n%s", r.GetData("fixture"))
+	}
+	m := r.MustGetMatcher("matcher")
+	actual := `{"id": "0001", "name": "Ethan Hunt", "template": "Hello ${{NAME}}"}`
+	matched, err := m.Match(actual)
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(actual))
+	}
+
+	// Variables in lenient sections are left as they are if undefined
+	r = MustReader(NewMultipartReaderFromFile("test_asset/attributes1.txt", map[string]string{"NAME": "Ethan"}, matcher.JSONParserInstance))
+	if !strings.Contains(string(r.GetData("matcher")), `"id": "${{ID}}"`) {
+		t.Fatalf("data incorrect\n%s", r.GetData("matcher"))
+	}
+}
+
+func TestMultipartReader_Parser(t *testing.T) {
+	matcher.RegisterParser("test_yaml", matcher.YAMLParserInstance)
+	r := MustReader(NewMultipartReader([]byte("### key=matcher, parser=test_yaml\nname: \"{{Not(BeEmpty())}}\"\n"), nil, matcher.JSONParserInstance))
+	m := r.MustGetMatcher("matcher")
+	matched, err := m.Match("name: Ethan Hunt")
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+}
+
+func TestMultipartReader_Description(t *testing.T) {
+	r := MustReader(NewMultipartReader([]byte("### key=foo, format=json, not found, status=404\n{}"), nil, matcher.JSONParserInstance))
+	s := r.Sections()[0]
+	if s.Key != "foo" || s.Description != "not found, status=404" || !reflect.DeepEqual(s.Attributes, map[string]string{"format": "json"}) {
+		t.Fatalf("section incorrect: %+v", s)
+	}
+}
+
+func TestMultipartReader_AttributeErrors(t *testing.T) {
+	cases := []struct {
+		data     string
+		expected string
+	}{
+		{"### key=a, color=red\n{}", "section 'a' has unknown attribute color.  See Gosert doc."},
+		{"### key=c, fromat=yaml\n{}", "section 'c' has unknown attribute fromat.  See Gosert doc."},
+		{"### key=a, format=xml\n{}", "section 'a' has invalid format=xml, must be json or yaml"},
+		{"### key=a, parser=xml\n{}", "section 'a' has unknown parser=xml, see matcher.RegisterParser"},
+		{"### key=a, vars=loose\n{}", "section 'a' has invalid vars=loose, must be strict or lenient"},
+		{"### key=a, format=json, parser=json\n{}", "section 'a' cannot have both format= and parser="},
+	}
+	for _, c := range cases {
+		_, err := NewMultipartReader([]byte(c.data), nil, matcher.JSONParserInstance)
+		if err == nil || err.Error() != c.expected {
+			t.Fatalf("err of %s incorrect: %+v", c.data, err)
+		}
+	}
+}
+
+func TestMultipartReader_Sections(t *testing.T) {
+	r := MustReader(NewMultipartReaderFromFile("test_asset/extends1.txt", map[string]string{"USER": "0001"}, matcher.JSONParserInstance))
+	if keys := r.Keys(); !reflect.DeepEqual(keys, []string{"base_error", "error_404", "user_not_found"}) {
+		t.Fatalf("keys incorrect: %v", keys)
+	}
+
+	sections := r.Sections()
+	if len(sections) != 3 {
+		t.Fatalf("sections should have 3 elements but has %d", len(sections))
+	}
+	s := sections[1]
+	if s.Key != "error_404" || s.Description != "not found" || !reflect.DeepEqual(s.Attributes, map[string]string{"extends": "base_error"}) || s.StartLine != 12 || s.EndLine != 19 {
+		t.Fatalf("section incorrect: %+v", s)
+	}
+	if !strings.Contains(string(s.Raw), `"_gst_delete": ["traceId"]`) || string(s.Data) != string(r.GetData("error_404")) {
+		t.Fatalf("section data incorrect\n%s\n%s", s.Raw, s.Data)
+	}
+	s = sections[2]
+	if !strings.Contains(string(s.Raw), "${{USER}}") || !strings.Contains(string(s.Data), "user 0001 not found") {
+		t.Fatalf("section data incorrect\n%s\n%s", s.Raw, s.Data)
+	}
+
+	// A key defined again replaces the section, in the position of the last definition
+	r = MustReader(NewMultipartReader([]byte("### key=a\n{}\n### key=b\n{}\n### key=a, again\n{\"a\": 1}"), nil, matcher.JSONParserInstance))
+	if keys := r.Keys(); !reflect.DeepEqual(keys, []string{"b", "a"}) {
+		t.Fatalf("keys incorrect: %v", keys)
+	}
+	if s := r.Sections()[1]; s.Description != "again" || s.StartLine != 5 || s.EndLine != 6 {
+		t.Fatalf("section incorrect: %+v", s)
+	}
+}
+
+func TestMultipartReader_ExtendsEscapedKeys(t *testing.T) {
+	r := MustReader(NewMultipartReader([]byte("### key=a\n{\"a\\\"b\": 1, \"c\": {\"d\\\\e\": \"f\"}}\n### key=b, extends=a\n{\"c\": {\"g\": 2}}"), nil, matcher.JSONParserInstance))
+	var actual interface{}
+	if err := json.Unmarshal(r.GetData("b"), &actual); err != nil {
+		t.Fatalf("err should be nil but was %+v\n%s", err, r.GetData("b"))
+	}
+	expected := map[string]interface{}{"a\"b": float64(1), "c": map[string]interface{}{"d\\e": "f", "g": float64(2)}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("data incorrect\n%s", r.GetData("b"))
+	}
+}
+
+func TestMultipartReader_ExtendsOtherFormat(t *testing.T) {
+	data := []byte(`### key=base, format=yaml
+msg: 'say "hi"'
+id: 12345678901234567890
+
+### key=child, extends=base
+{"name": "Ethan Hunt"}
+`)
+	r := MustReader(NewMultipartReader(data, nil, matcher.JSONParserInstance))
+	if s := string(r.GetData("child")); !strings.Contains(s, `"msg":"say \"hi\""`) || !strings.Contains(s, `"id":12345678901234567890`) {
+		t.Fatalf("data incorrect\n%s", s)
+	}
+	m := r.MustGetMatcher("child")
+	matched, err := m.Match(`{"msg": "say \"hi\"", "id": 12345678901234567890, "name": "Ethan Hunt"}`)
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+}
@@ -0,0 +1,131 @@
+package gosert
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Report is a machine-readable result of the last match, e.g. for CI dashboards.
+type Report struct {
+	// Golden is the golden file the expected value was read from, if any.
+	Golden   string    `json:"golden,omitempty"`
+	Matched  bool      `json:"matched"`
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// Failure is a single failure in a Report.
+type Failure struct {
+	Path     string `json:"path"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	// Kind is one of "mismatch", "missing", "type", "unexpected" (see WithStrictObjects) or "error".
+	Kind  string `json:"kind"`
+	Error string `json:"error,omitempty"`
+	// Location is where the expected value is defined in the golden file.
+	Location string `json:"location,omitempty"`
+}
+
+// JUnitTestCase is a JUnit XML <testcase> element.  It can be added to an existing test suite.
+type JUnitTestCase struct {
+	XMLName   xml.Name      `xml:"testcase"`
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr,omitempty"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// JUnitFailure is a JUnit XML <failure> element.
+type JUnitFailure struct {
+	Message  string `xml:"message,attr"`
+	Type     string `xml:"type,attr"`
+	Contents string `xml:",chardata"`
+}
+
+type junitTestSuite struct {
+	XMLName   xml.Name        `xml:"testsuite"`
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []JUnitTestCase `xml:"testcase"`
+}
+
+// Report returns a report of the last match.
+func (m *Matcher) Report() *Report {
+	r := &Report{
+		Golden:  m.source.Name,
+		Matched: len(m.failures) == 0,
+	}
+	for _, f := range m.failures {
+		failure := Failure{
+			Path:     f.Path,
+			Expected: f.Expected,
+			Actual:   f.Actual,
+			Kind:     string(f.Kind),
+			Location: f.Location,
+		}
+		if f.Err != nil {
+			failure.Error = f.Err.Error()
+		}
+		r.Failures = append(r.Failures, failure)
+	}
+	return r
+}
+
+// JSON returns r encoded as JSON.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// JUnitTestCase returns r as a JUnit test case with the given name.  The JSON report is attached as system output.
+func (r *Report) JUnitTestCase(name string) JUnitTestCase {
+	tc := JUnitTestCase{
+		Name:      name,
+		ClassName: r.Golden,
+	}
+	if bs, err := r.JSON(); err == nil {
+		tc.SystemOut = string(bs)
+	}
+	if !r.Matched {
+		var lines []string
+		for _, f := range r.Failures {
+			lines = append(lines, f.String())
+		}
+		tc.Failure = &JUnitFailure{
+			Message:  fmt.Sprintf("%d failures", len(r.Failures)),
+			Type:     "gosert",
+			Contents: strings.Join(lines, "\n"),
+		}
+	}
+	return tc
+}
+
+// JUnit returns r as a JUnit XML test suite with a single test case.
+func (r *Report) JUnit(name string) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:      name,
+		Tests:     1,
+		TestCases: []JUnitTestCase{r.JUnitTestCase(name)},
+	}
+	if !r.Matched {
+		suite.Failures = 1
+	}
+	bs, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), bs...), nil
+}
+
+// String returns string.
+func (f Failure) String() string {
+	s := fmt.Sprintf("[%s] path = %s, expected = %s, actual = %s", f.Kind, f.Path, f.Expected, f.Actual)
+	if f.Error != "" {
+		s += ", error = " + f.Error
+	}
+	if f.Location != "" {
+		s += ", at " + f.Location
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package gosert
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMatcher_Report(t *testing.T) {
+	m := MustMatcher(NewJSONMatcherFromFile(
+		"test_asset/golden1.json",
+		map[string]string{
+			"VAR":       "value1_0",
+			"TIMESTAMP": "2018-10-05T12:13:14.000Z",
+		},
+	))
+	act := `{
+			"field0": "value1",
+			"field1": {
+				"field1_0": 10,
+				"field1_1": {}
+			}
+		}`
+
+	matched, _ := m.Match(act)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+
+	r := m.Report()
+	if r.Matched {
+		t.Fatalf("report should not be matched")
+	}
+	if r.Golden != "test_asset/golden1.json" {
+		t.Fatalf("report golden incorrect, %s", r.Golden)
+	}
+	if len(r.Failures) != 3 {
+		t.Fatalf("report should have 3 failures but has %+v", r.Failures)
+	}
+	expected := []Failure{
+		{Path: ".field0", Expected: "value0", Actual: "value1", Kind: "mismatch", Location: "test_asset/golden1.json:2:13"},
+		{Path: ".field1.field1_0", Expected: "value1_0", Actual: "10", Kind: "error", Location: "test_asset/golden1.json:4:17"},
+		{Path: ".field1.field1_1.field1_1_0", Expected: "{{BeTimestamp(2018-10-05T12:13:14.000Z, 5000)}}", Actual: "", Kind: "missing", Location: "test_asset/golden1.json:6:21"},
+	}
+	for i, e := range expected {
+		f := r.Failures[i]
+		if f.Path != e.Path || f.Expected != e.Expected || f.Actual != e.Actual || f.Kind != e.Kind || f.Location != e.Location {
+			t.Fatalf("failure %d incorrect, %+v", i, f)
+		}
+	}
+
+	bs, err := r.JSON()
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(bs, &decoded); err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if len(decoded.Failures) != 3 || decoded.Failures[1].Error == "" {
+		t.Fatalf("JSON() result incorrect, %s", string(bs))
+	}
+
+	bs, err = r.JUnit("golden1")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if !strings.Contains(string(bs), `<testsuite name="golden1" tests="1" failures="1">`) || !strings.Contains(string(bs), `<failure message="3 failures" type="gosert">`) {
+		t.Fatalf("JUnit() result incorrect, %s", string(bs))
+	}
+}
+
+func TestMatcher_Report_Matched(t *testing.T) {
+	m := MustMatcher(NewJSONMatcher([]byte(`{"foo": "bar"}`), nil))
+	matched, err := m.Match(`{"foo": "bar"}`)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	if !matched {
+		t.Fatalf("matched should be true")
+	}
+
+	r := m.Report()
+	if !r.Matched || len(r.Failures) != 0 {
+		t.Fatalf("report should be matched but was %+v", r)
+	}
+	tc := r.JUnitTestCase("foo")
+	if tc.Failure != nil {
+		t.Fatalf("test case should not have failure but was %+v", tc.Failure)
+	}
+}
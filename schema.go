@@ -0,0 +1,24 @@
+package gosert
+
+import (
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+// NewSchemaMatcher returns a matcher that validates documents (`string` or `[]byte`) with the JSON Schema in data.
+// Documents are parsed with the parser set by WithParser.  See matcher.Schema for the supported keywords.
+func NewSchemaMatcher(data []byte, opts ...Option) (*matcher.SchemaMatcher, error) {
+	schema, err := matcher.ParseSchema(data)
+	if err != nil {
+		return nil, err
+	}
+	return matcher.NewSchemaMatcher(schema, newOptions(opts).parser), nil
+}
+
+// NewSchemaMatcherFromFile returns a matcher that validates documents with the JSON Schema file at path.
+func NewSchemaMatcherFromFile(path string, opts ...Option) (*matcher.SchemaMatcher, error) {
+	schema, err := matcher.ParseSchemaFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return matcher.NewSchemaMatcher(schema, newOptions(opts).parser), nil
+}
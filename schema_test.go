@@ -0,0 +1,82 @@
+package gosert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+func TestNewSchemaMatcherFromFile(t *testing.T) {
+	m, err := NewSchemaMatcherFromFile("test_asset/schema1.json")
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	matched, err := m.Match(`{"userId": "0001", "name": "Ethan Hunt", "age": 36, "connections": [{"id": "0002", "relationship": "ex"}]}`)
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+	matched, _ = m.Match(`{"userId": "1", "name": "Ethan Hunt", "connections": [{"relationship": "team mate"}]}`)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	var msgs []string
+	for _, e := range m.Errors() {
+		msgs = append(msgs, e.Error())
+	}
+	expected := `.connections[0].id: field is required
+.connections[0].relationship: value must be one of ["ex","unclear"]
+.userId: value must match pattern ^\d{4}$`
+	if strings.Join(msgs, "\n") != expected {
+		t.Fatalf("errors incorrect\n%s", strings.Join(msgs, "\n"))
+	}
+}
+
+func TestNew_MatchSchema(t *testing.T) {
+	m := MustMatcher(New([]byte(`{"user": "{{MatchSchema(test_asset/schema1.json)}}", "status": "ok"}`)))
+	matched, err := m.Match(`{"user": {"userId": "0001", "name": "Ethan Hunt", "connections": []}, "status": "ok"}`)
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+	matched, _ = m.Match(`{"user": {"userId": "0001", "name": "", "connections": []}, "status": "ok"}`)
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if msg := m.FailureMessage(nil); !strings.Contains(msg, ".user.name: length must be >= 1 but is 0") {
+		t.Fatalf("failure message incorrect, %s", msg)
+	}
+}
+
+func TestMultipartReader_MatchSchema(t *testing.T) {
+	r, err := NewMultipartReaderFromFile("test_asset/schema/golden.txt", nil, matcher.JSONParserInstance)
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+
+	// Schema paths are relative to the golden file, and YAML sections parse the actual value as YAML
+	m := r.MustGetMatcher("user")
+	matched, err := m.Match("user:\n  name: Ethan Hunt\n  deletedAt: null\nstatus: ok\n")
+	if !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+	matched, _ = m.Match("user:\n  name: Ethan Hunt\n  deletedAt: 2018-10-05\nstatus: ok\n")
+	if matched {
+		t.Fatalf("matched should be false")
+	}
+	if msg := m.FailureMessage(nil); !strings.Contains(msg, ".user.deletedAt: value must be null") {
+		t.Fatalf("failure message incorrect, %s", msg)
+	}
+
+	m = r.MustGetMatcher("not_user")
+	if matched, err := m.Match(`{"user": {"name": ""}}`); !matched || err != nil {
+		t.Fatalf("matched should be true but failed with %s", m.FailureMessage(nil))
+	}
+	if matched, _ := m.Match(`{"user": {"name": "Ethan Hunt"}}`); matched {
+		t.Fatalf("matched should be false")
+	}
+
+	diags, err := ValidateFile("test_asset/schema/golden.txt", matcher.JSONParserInstance)
+	if err != nil || len(diags) != 0 {
+		t.Fatalf("there should be no diagnostics but were %+v, %+v", diags, err)
+	}
+}
@@ -0,0 +1,55 @@
+// Package assert provides testify-style assertions for golden files.  The functions mirror
+// github.com/stretchr/testify/assert and can be used alongside it.
+package assert
+
+import (
+	"fmt"
+
+	"github.com/mina-akimi/gosert/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// JSONMatches asserts that actual (a `string` or `[]byte`) matches the JSON golden value.  vars is used to replace
+// variables in golden.
+//
+//	assert.JSONMatches(t, []byte(`{"id": "{{Not(BeEmpty())}}"}`), actual, nil)
+func JSONMatches(t assert.TestingT, golden []byte, actual interface{}, vars map[string]string, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	m, err := gosert.NewJSONMatcher(golden, vars)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("Invalid golden value: %s", err.Error()), msgAndArgs...)
+	}
+	return matches(t, m, actual, msgAndArgs...)
+}
+
+// JSONMatchesGolden asserts that actual (a `string` or `[]byte`) matches the JSON golden file at goldenPath.  vars is
+// used to replace variables in the golden file.
+//
+//	assert.JSONMatchesGolden(t, "testdata/user.json", actual, map[string]string{"NOW": now})
+func JSONMatchesGolden(t assert.TestingT, goldenPath string, actual interface{}, vars map[string]string, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	m, err := gosert.NewJSONMatcherFromFile(goldenPath, vars)
+	if err != nil {
+		return assert.Fail(t, fmt.Sprintf("Invalid golden file %s: %s", goldenPath, err.Error()), msgAndArgs...)
+	}
+	return matches(t, m, actual, msgAndArgs...)
+}
+
+func matches(t assert.TestingT, m *gosert.Matcher, actual interface{}, msgAndArgs ...interface{}) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	matched, err := m.Match(actual)
+	if matched && err == nil {
+		return true
+	}
+	msg := m.FailureMessage(actual)
+	if err != nil {
+		msg = fmt.Sprintf("%s\n%s", err.Error(), msg)
+	}
+	return assert.Fail(t, fmt.Sprintf("Not matching golden value:\n%s", msg), msgAndArgs...)
+}
@@ -0,0 +1,65 @@
+package assert
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// mockT records errors instead of failing the test.
+type mockT struct {
+	errors []string
+}
+
+func (t *mockT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestJSONMatchesGolden(t *testing.T) {
+	vars := map[string]string{
+		"VAR":       "value1_0",
+		"TIMESTAMP": "2018-10-05T12:13:14.000Z",
+	}
+	act := `{
+			"field0": "value0",
+			"field1": {
+				"field1_0": "value1_0",
+				"field1_1": {
+					"field1_1_0": "2018-10-05T12:13:14.123Z"
+				}
+			}
+		}`
+
+	mt := &mockT{}
+	if !JSONMatchesGolden(mt, "../../test_asset/golden1.json", act, vars) {
+		t.Fatalf("JSONMatchesGolden() should succeed but failed with %+v", mt.errors)
+	}
+
+	mt = &mockT{}
+	if JSONMatchesGolden(mt, "../../test_asset/golden1.json", strings.Replace(act, "value0", "value1", 1), vars, "user %s", "0001") {
+		t.Fatalf("JSONMatchesGolden() should fail")
+	}
+	if len(mt.errors) != 1 {
+		t.Fatalf("JSONMatchesGolden() should report 1 error but reported %+v", mt.errors)
+	}
+	for _, s := range []string{"Error Trace:", "Not matching golden value:", "path = .field0", "-value0", "+value1", "Messages:", "user 0001"} {
+		if !strings.Contains(mt.errors[0], s) {
+			t.Fatalf("error should contain %q but was %s", s, mt.errors[0])
+		}
+	}
+}
+
+func TestJSONMatches(t *testing.T) {
+	mt := &mockT{}
+	if !JSONMatches(mt, []byte(`{"id": "{{Not(BeEmpty())}}"}`), `{"id": "0001"}`, nil) {
+		t.Fatalf("JSONMatches() should succeed but failed with %+v", mt.errors)
+	}
+
+	mt = &mockT{}
+	if JSONMatches(mt, []byte(`{"id": "${{ID}}"}`), `{"id": "0001"}`, nil) {
+		t.Fatalf("JSONMatches() should fail")
+	}
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "Invalid golden value") {
+		t.Fatalf("JSONMatches() should report invalid golden value but reported %+v", mt.errors)
+	}
+}
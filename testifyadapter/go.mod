@@ -0,0 +1,12 @@
+module github.com/mina-akimi/gosert/v2/testifyadapter
+
+go 1.14
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/mina-akimi/gosert/v2 v2.0.0
+	github.com/stretchr/testify v1.6.0
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/mina-akimi/gosert/v2 => ../
@@ -0,0 +1,28 @@
+// Package require is like package assert, but the test is stopped on failure.  The functions mirror
+// github.com/stretchr/testify/require and can be used alongside it.
+package require
+
+import (
+	"github.com/mina-akimi/gosert/v2/testifyadapter/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// JSONMatches is like assert.JSONMatches, but calls t.FailNow on failure.
+func JSONMatches(t require.TestingT, golden []byte, actual interface{}, vars map[string]string, msgAndArgs ...interface{}) {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if !assert.JSONMatches(t, golden, actual, vars, msgAndArgs...) {
+		t.FailNow()
+	}
+}
+
+// JSONMatchesGolden is like assert.JSONMatchesGolden, but calls t.FailNow on failure.
+func JSONMatchesGolden(t require.TestingT, goldenPath string, actual interface{}, vars map[string]string, msgAndArgs ...interface{}) {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if !assert.JSONMatchesGolden(t, goldenPath, actual, vars, msgAndArgs...) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,33 @@
+package require
+
+import (
+	"testing"
+)
+
+// mockT records failures instead of failing the test.
+type mockT struct {
+	errors int
+	failed bool
+}
+
+func (t *mockT) Errorf(format string, args ...interface{}) {
+	t.errors++
+}
+
+func (t *mockT) FailNow() {
+	t.failed = true
+}
+
+func TestJSONMatches(t *testing.T) {
+	mt := &mockT{}
+	JSONMatches(mt, []byte(`{"id": "0001"}`), `{"id": "0001"}`, nil)
+	if mt.failed || mt.errors != 0 {
+		t.Fatalf("JSONMatches() should succeed")
+	}
+
+	mt = &mockT{}
+	JSONMatches(mt, []byte(`{"id": "0001"}`), `{"id": "0002"}`, nil)
+	if !mt.failed || mt.errors != 1 {
+		t.Fatalf("JSONMatches() should call FailNow")
+	}
+}
@@ -0,0 +1,319 @@
+package gosert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+var patternYAMLLine = regexp.MustCompile(`^yaml: line (\d+):`)
+
+// Diagnostic is a problem in a golden file found by Validate.
+type Diagnostic struct {
+	Position matcher.Position
+	Message  string
+}
+
+// String returns string, e.g. "golden.json:12:5: unknown function in '{{BeFoo()}}'".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Position.String(), d.Message)
+}
+
+// Validate statically checks a golden file or a multipart file (see MultipartReader) for problems that would
+// otherwise only surface when matching, e.g. unknown functions, bad function arguments, invalid generator variables,
+// inconsistent array markers, duplicate section keys and section headers without key=.  Undefined variables are
+// reported if WithVars is given.  Diagnostics are sorted by position.
+func Validate(golden []byte, parser matcher.Parser, opts ...Option) []Diagnostic {
+	return validate("", golden, parser, newOptions(opts))
+}
+
+// ValidateFile is like Validate, but the golden file is read from path.  Positions of diagnostics have path as file.
+func ValidateFile(path string, parser matcher.Parser, opts ...Option) ([]Diagnostic, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return validate(path, bs, parser, newOptions(opts)), nil
+}
+
+func validate(name string, data []byte, parser matcher.Parser, opts *options) []Diagnostic {
+	v := &validator{
+		name:   name,
+		parser: parser,
+		opts:   opts,
+		gen:    matcher.NewGenerator(0, opts.clock()),
+	}
+	if isMultipart(data) {
+		v.validateMultipart(data)
+	} else {
+		v.validateDocument(header{}, data, nil)
+	}
+	sort.SliceStable(v.diags, func(i, j int) bool {
+		pi, pj := v.diags[i].Position, v.diags[j].Position
+		if pi.Line != pj.Line {
+			return pi.Line < pj.Line
+		}
+		return pi.Col < pj.Col
+	})
+	return v.diags
+}
+
+type validator struct {
+	name   string
+	parser matcher.Parser
+	opts   *options
+	gen    *matcher.Generator
+	diags  []Diagnostic
+}
+
+func (v *validator) add(pos matcher.Position, format string, args ...interface{}) {
+	v.diags = append(v.diags, Diagnostic{
+		Position: pos,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) linePosition(line int) matcher.Position {
+	return matcher.Position{
+		File: v.name,
+		Line: line,
+		Col:  1,
+	}
+}
+
+func isMultipart(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if patternHeader.MatchString(strings.TrimSpace(scanner.Text())) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMultipart splits data into sections the same way as MultipartReader and validates each section.
+func (v *validator) validateMultipart(data []byte) {
+	sections, _, err := scanSections(bytes.NewReader(data))
+	if err != nil {
+		v.add(v.linePosition(1), "%s", err.Error())
+		return
+	}
+	// line of the header of each key
+	keys := map[string]int{}
+	// parent of each key that extends another section
+	parents := map[string]string{}
+	for _, s := range sections {
+		if s.line == 0 {
+			v.add(v.linePosition(s.lines[0]), "content before the first section header")
+			continue
+		}
+		if err := s.check(); err != nil {
+			v.add(v.linePosition(s.line), "%s", err.Error())
+			// Validate the section with known attributes only
+			s.attrs = map[string]string{}
+		}
+		if s.key == "" {
+			v.add(v.linePosition(s.line), "section header has no key=")
+			continue
+		}
+		if first, ok := keys[s.key]; ok {
+			v.add(v.linePosition(s.line), "duplicate section key '%s', first defined at line %d", s.key, first)
+		} else {
+			keys[s.key] = s.line
+		}
+		if parent, ok := s.attrs[AttrExtends]; ok {
+			parents[s.key] = parent
+		}
+		if len(s.object) == 0 {
+			v.add(v.linePosition(s.line), "section '%s' is empty", s.key)
+			continue
+		}
+		v.validateDocument(s.header, s.object, s.lines)
+	}
+
+	// In file order, so that a cycle is reported once, at its first section
+	extending := sortedKeys(parents)
+	sort.SliceStable(extending, func(i, j int) bool {
+		return keys[extending[i]] < keys[extending[j]]
+	})
+	inCycle := map[string]bool{}
+	for _, k := range extending {
+		parent := parents[k]
+		if _, ok := keys[parent]; !ok {
+			v.add(v.linePosition(keys[k]), "section '%s' extends unknown section '%s'", k, parent)
+			continue
+		}
+		if inCycle[k] {
+			continue
+		}
+		// Follow the chain of parents, it is a cycle if it comes back to k before it is longer than the number of
+		// sections
+		chain := []string{k}
+		for ; len(chain) <= len(parents) && parent != ""; parent = parents[parent] {
+			if parent != k {
+				chain = append(chain, parent)
+				continue
+			}
+			if len(chain) == 1 {
+				v.add(v.linePosition(keys[k]), "section '%s' extends itself", k)
+			} else {
+				v.add(v.linePosition(keys[k]), "sections extend each other: %s -> %s", strings.Join(chain, " -> "), k)
+			}
+			for _, c := range chain {
+				inCycle[c] = true
+			}
+			break
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateDocument validates a golden document, or a section with header h.  lines maps each line in data to a line in
+// the file.
+func (v *validator) validateDocument(h header, data []byte, lines []int) {
+	parser := h.parser(v.parser)
+	// Undefined variables and invalid generator variables are replaced with placeholders, so that the rest of the
+	// document can be checked
+	vars := map[string]string{}
+	for k, val := range v.opts.vars {
+		vars[k] = val
+	}
+	var undefined, invalid []matcher.Variable
+	var invalidErrs []error
+	for _, variable := range matcher.Variables(data) {
+		if strings.HasPrefix(variable.Name, "@") {
+			if _, err := v.gen.Value(variable.Name[1:]); err != nil {
+				invalid = append(invalid, variable)
+				invalidErrs = append(invalidErrs, err)
+				setPlaceholder(vars, variable.Name, data, variable.Offset)
+			}
+			continue
+		}
+		if _, ok := v.opts.vars[variable.Name]; !ok {
+			if v.opts.vars != nil && h.attrs[AttrVars] != "lenient" {
+				undefined = append(undefined, variable)
+			}
+			setPlaceholder(vars, variable.Name, data, variable.Offset)
+		}
+	}
+
+	source, err := matcher.NewSource(v.name, data, lines, vars, v.gen)
+	if err != nil {
+		pos := v.linePosition(1)
+		if len(lines) > 0 {
+			pos = v.linePosition(lines[0])
+		}
+		v.add(pos, "%s", err.Error())
+		return
+	}
+	for _, variable := range undefined {
+		v.add(source.OriginalPosition(variable.Offset), "variable '%s' undefined", variable.Name)
+	}
+	for i, variable := range invalid {
+		v.add(source.OriginalPosition(variable.Offset), "%s", invalidErrs[i].Error())
+	}
+
+	inc := newIncluder(v.opts.vars, v.gen)
+	inc.placeholders = true
+	if ie := inc.expand(source, sourceID(v.name, h.key)); ie != nil {
+		v.add(source.Position(ie.offset), "%s", ie.err.Error())
+		return
+	}
+
+	doc := matcher.DocumentNode(source.Data)
+	if err := v.validateSyntax(source, doc, parser); err != nil {
+		return
+	}
+	for _, e := range matcher.Lint(doc, parser, functionsFor(v.name, parser, v.opts.functions)) {
+		pos, ok := source.Locate(e.Path, parser)
+		if !ok {
+			pos = source.OriginalPosition(0)
+		}
+		v.add(pos, "%s", e.Error())
+	}
+}
+
+// validateSyntax reports and returns the error if doc cannot be parsed.
+func (v *validator) validateSyntax(source *matcher.Source, doc matcher.Node, parser matcher.Parser) error {
+	data := doc.Value
+	prefix := 0
+	if doc.Type == matcher.Array {
+		// Arrays are only valid as fixtures, wrap them to validate with the parser
+		data = append([]byte(`{"_":`), append(data, '}')...)
+		prefix = len(`{"_":`)
+	}
+	err := parser.ValidateObject(data)
+	if err == nil {
+		return nil
+	}
+	start := bytes.Index(source.Data, doc.Value)
+	pos := source.Position(start)
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		// Offset is after the invalid character
+		pos = source.Position(start + int(e.Offset) - 1 - prefix)
+	case *json.UnmarshalTypeError:
+		pos = source.Position(start + int(e.Offset) - prefix)
+	default:
+		if m := patternYAMLLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			pos = source.Position(lineOffset(source.Data, line))
+		}
+	}
+	v.add(pos, "invalid document: %s", err.Error())
+	return err
+}
+
+// setPlaceholder sets the value of the unknown variable name at offset in data: matcher.Placeholder if all occurrences
+// are inside strings, otherwise a number.
+func setPlaceholder(vars map[string]string, name string, data []byte, offset int) {
+	if vars[name] != "0" {
+		vars[name] = placeholderValue(data, offset)
+	}
+}
+
+func placeholderValue(data []byte, offset int) string {
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	quotes := 0
+	for i := start; i < offset; i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			quotes++
+		}
+	}
+	if quotes%2 == 1 {
+		return matcher.Placeholder
+	}
+	return "0"
+}
+
+// lineOffset returns the offset of the start of line (1-based) in data.
+func lineOffset(data []byte, line int) int {
+	offset := 0
+	for i := 1; i < line; i++ {
+		next := bytes.IndexByte(data[offset:], '\n')
+		if next < 0 {
+			break
+		}
+		offset += next + 1
+	}
+	return offset
+}
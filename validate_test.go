@@ -0,0 +1,80 @@
+package gosert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mina-akimi/gosert/v2/matcher"
+)
+
+func diagnosticStrings(diags []Diagnostic) string {
+	var strs []string
+	for _, d := range diags {
+		strs = append(strs, d.String())
+	}
+	return strings.Join(strs, "\n")
+}
+
+func TestValidateFile(t *testing.T) {
+	diags, err := ValidateFile("test_asset/lint1.txt", matcher.JSONParserInstance, WithVars(map[string]string{}))
+	if err != nil {
+		t.Fatalf("err should be nil but was %+v", err)
+	}
+	expected := `test_asset/lint1.txt:8:1: section header has no key=
+test_asset/lint1.txt:15:9: .id: invalid arguments in '{{BeUUID(v9)}}': BeUUID: version must be one of v1 to v5 but was v9
+test_asset/lint1.txt:16:11: .name: unknown function in '{{BeNice()}}'
+test_asset/lint1.txt:17:31: variable 'NOW' undefined
+test_asset/lint1.txt:18:17: generator '@now+1x' has invalid offset: time: unknown unit "x" in duration "1x"
+test_asset/lint1.txt:19:18: .connections: cannot have some elements with '_gst_index' and some with '_gst_id'
+test_asset/lint1.txt:25:1: duplicate section key 'matcher', first defined at line 13
+test_asset/lint1.txt:25:1: section 'matcher' is empty
+test_asset/lint1.txt:26:1: section 'empty' is empty`
+	if diagnosticStrings(diags) != expected {
+		t.Fatalf("diagnostics incorrect\n%s", diagnosticStrings(diags))
+	}
+
+	// Without vars, undefined variables are not reported
+	diags, _ = ValidateFile("test_asset/multipart1.txt", matcher.JSONParserInstance)
+	if len(diags) != 0 {
+		t.Fatalf("diagnostics should be empty\n%s", diagnosticStrings(diags))
+	}
+	diags, _ = ValidateFile("test_asset/golden1.json", matcher.JSONParserInstance)
+	if len(diags) != 0 {
+		t.Fatalf("diagnostics should be empty\n%s", diagnosticStrings(diags))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		golden   string
+		opts     []Option
+		expected string
+	}{
+		{`{"a": "{{Use(status)}}"}`, nil, ``},
+		{`{"a": "{{Use(status)}}"}`, []Option{WithMatcher("state", nil)}, `1:7: .a: invalid arguments in '{{Use(status)}}': Use: matcher status is not defined`},
+		{"{\n  \"a\": 1,\n  \"b\": ${{B}}\n  \"c\": 2\n}", nil, `4:3: invalid document: invalid character '"' after object key:value pair`},
+		{"{\n  \"a\": 1,\n  \"b\": ${{B}},\n}", []Option{WithVars(map[string]string{"B": "2"})}, `4:1: invalid document: invalid character '}' looking for beginning of object key string`},
+		{"{\"a\": \"x\"}\n### key=a\n{}", nil, `1:1: content before the first section header`},
+		{"### key=a, color=red\n{}\n### key=b, vars=lenient\n{\"b\": \"${{B}}\"}", []Option{WithVars(map[string]string{})}, "1:1: section 'a' has unknown attribute color.  See Gosert doc."},
+		{"### key=a, format=yaml\na: \"{{BeNice()}}\"", nil, "2:1: .a: unknown function in '{{BeNice()}}'"},
+		{"### key=a, extends=b\n{}\n### key=c, extends=d\n{}\n### key=d, extends=c\n{}", nil, "1:1: section 'a' extends unknown section 'b'\n3:1: sections extend each other: c -> d -> c"},
+		{"### key=a, extends=a\n{}\n### key=b, extends=c\n{}\n### key=c, extends=d\n{}\n### key=d, extends=c\n{}", nil, "1:1: section 'a' extends itself\n5:1: sections extend each other: c -> d -> c"},
+	}
+	for _, c := range cases {
+		diags := Validate([]byte(c.golden), matcher.JSONParserInstance, c.opts...)
+		if diagnosticStrings(diags) != c.expected {
+			t.Fatalf("diagnostics of %s incorrect\n%s", c.golden, diagnosticStrings(diags))
+		}
+	}
+}
+
+func TestValidateFile_Include(t *testing.T) {
+	diags, _ := ValidateFile("test_asset/include/user.json", matcher.JSONParserInstance)
+	if len(diags) != 0 {
+		t.Fatalf("diagnostics should be empty\n%s", diagnosticStrings(diags))
+	}
+	diags, _ = ValidateFile("test_asset/include/cycle_a.json", matcher.JSONParserInstance)
+	if len(diags) != 1 || !strings.HasPrefix(diags[0].String(), "test_asset/include/cycle_a.json:2:8: cannot include cycle_b.json: ") {
+		t.Fatalf("diagnostics incorrect\n%s", diagnosticStrings(diags))
+	}
+}